@@ -0,0 +1,203 @@
+package mojilog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// levelState is the JSON document served by the level handler
+type levelState struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// levelChange is the JSON body accepted by PUT and POST. A module set to null
+// loses its override. With a TTL the change reverts after that duration.
+type levelChange struct {
+	Level   string             `json:"level,omitempty"`
+	Modules map[string]*string `json:"modules,omitempty"`
+	TTL     string             `json:"ttl,omitempty"`
+}
+
+// pendingRevert remembers the level to restore when a temporary change expires
+type pendingRevert struct {
+	timer    *time.Timer
+	original *slog.Level // nil when there was no module override
+}
+
+var (
+	revertMu sync.Mutex
+	// pendingReverts are the temporary changes of all level handlers, by module
+	// pattern, "" is the global level. Reset cancels them.
+	pendingReverts = make(map[string]*pendingRevert)
+)
+
+// LevelHandler serves the global and per-module log levels over HTTP
+type LevelHandler struct{}
+
+// NewLevelHandler creates a handler to mount at e.g. /debug/loglevel.
+//
+// GET returns the levels:
+//
+//	{"level": "INFO", "modules": {"myapp/internal/cache/*": "DEBUG"}}
+//
+// PUT and POST change them, optionally only for a while:
+//
+//	{"level": "debug", "modules": {"myapp/internal/cache/*": "trace", "myapp/db": null}, "ttl": "10m"}
+func NewLevelHandler() *LevelHandler {
+	return &LevelHandler{}
+}
+
+// ServeHTTP implements http.Handler
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		if err := h.apply(r); err != nil {
+			writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		writeLevelJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	state := levelState{Level: LevelName(GetLevel()), Modules: make(map[string]string)}
+	for pattern, level := range ModuleLevels() {
+		state.Modules[pattern] = LevelName(level)
+	}
+	writeLevelJSON(w, http.StatusOK, state)
+}
+
+// apply validates the whole change before touching any level
+func (h *LevelHandler) apply(r *http.Request) error {
+	var change levelChange
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&change); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	var ttl time.Duration
+	if change.TTL != "" {
+		d, err := time.ParseDuration(change.TTL)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid ttl %q", change.TTL)
+		}
+		ttl = d
+	}
+
+	parse := func(name string) (*slog.Level, error) {
+		level, ok := LevelByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown level %q", name)
+		}
+		return &level, nil
+	}
+
+	var global *slog.Level
+	if change.Level != "" {
+		level, err := parse(change.Level)
+		if err != nil {
+			return err
+		}
+		global = level
+	}
+	modules := make(map[string]*slog.Level, len(change.Modules))
+	for pattern, name := range change.Modules {
+		if pattern == "" {
+			return fmt.Errorf("empty module pattern")
+		}
+		modules[pattern] = nil
+		if name != nil {
+			level, err := parse(*name)
+			if err != nil {
+				return fmt.Errorf("module %q: %w", pattern, err)
+			}
+			modules[pattern] = level
+		}
+	}
+	if global == nil && len(modules) == 0 {
+		return fmt.Errorf("nothing to change")
+	}
+
+	revertMu.Lock()
+	defer revertMu.Unlock()
+
+	if global != nil {
+		current := GetLevel()
+		scheduleRevert("", &current, ttl)
+		SetLevel(*global)
+	}
+	for pattern, level := range modules {
+		var current *slog.Level
+		if l, ok := ModuleLevel(pattern); ok {
+			current = &l
+		}
+		scheduleRevert(pattern, current, ttl)
+		if level == nil {
+			ClearModuleLevel(pattern)
+		} else {
+			SetModuleLevel(pattern, *level)
+		}
+	}
+	return nil
+}
+
+// scheduleRevert arranges for target to revert after ttl, or cancels a pending
+// revert when the change is permanent. Repeated temporary changes revert to
+// the level from before the first one. The caller must hold revertMu.
+func scheduleRevert(target string, current *slog.Level, ttl time.Duration) {
+	original := current
+	if p, ok := pendingReverts[target]; ok {
+		p.timer.Stop()
+		original = p.original
+		delete(pendingReverts, target)
+	}
+	if ttl == 0 {
+		return
+	}
+
+	p := &pendingRevert{original: original}
+	p.timer = time.AfterFunc(ttl, func() {
+		revertMu.Lock()
+		defer revertMu.Unlock()
+
+		// A newer change or Reset may have replaced this revert
+		if pendingReverts[target] != p {
+			return
+		}
+		delete(pendingReverts, target)
+		switch {
+		case target == "":
+			SetLevel(*p.original)
+		case p.original == nil:
+			ClearModuleLevel(target)
+		default:
+			SetModuleLevel(target, *p.original)
+		}
+	})
+	pendingReverts[target] = p
+}
+
+// cancelReverts drops every pending revert
+func cancelReverts() {
+	revertMu.Lock()
+	defer revertMu.Unlock()
+
+	for target, p := range pendingReverts {
+		p.timer.Stop()
+		delete(pendingReverts, target)
+	}
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
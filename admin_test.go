@@ -0,0 +1,125 @@
+package mojilog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelHandler(t *testing.T) {
+	defer SetLevel(slog.LevelInfo)
+	defer ClearModuleLevel("myapp/cache/*")
+	SetLevel(slog.LevelInfo)
+
+	server := httptest.NewServer(NewLevelHandler())
+	defer server.Close()
+
+	do := func(method, body string) (int, map[string]any) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var doc map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, doc
+	}
+
+	status, doc := do(http.MethodGet, "")
+	if status != http.StatusOK || doc["level"] != "INFO" {
+		t.Fatalf("GET: expected 200 with INFO, got %d %v", status, doc)
+	}
+
+	status, doc = do(http.MethodPut, `{"level":"debug","modules":{"myapp/cache/*":"trace"}}`)
+	if status != http.StatusOK || doc["level"] != "DEBUG" {
+		t.Fatalf("PUT: expected 200 with DEBUG, got %d %v", status, doc)
+	}
+	if modules, _ := doc["modules"].(map[string]any); modules["myapp/cache/*"] != "TRACE" {
+		t.Errorf("PUT: expected module override, got %v", doc["modules"])
+	}
+	if GetLevel() != slog.LevelDebug {
+		t.Errorf("expected global level debug, got %v", GetLevel())
+	}
+
+	status, doc = do(http.MethodPost, `{"modules":{"myapp/cache/*":null}}`)
+	if status != http.StatusOK {
+		t.Fatalf("POST: expected 200, got %d %v", status, doc)
+	}
+	if _, ok := ModuleLevel("myapp/cache/*"); ok {
+		t.Error("expected module override to be cleared")
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `{"level":"debug","ttl":"soon"}`, `{"lvl":"debug"}`, `{}`} {
+		if status, doc := do(http.MethodPut, body); status != http.StatusBadRequest || doc["error"] == nil {
+			t.Errorf("PUT %s: expected 400 with an error, got %d %v", body, status, doc)
+		}
+	}
+	if GetLevel() != slog.LevelDebug {
+		t.Errorf("rejected requests must not change the level, got %v", GetLevel())
+	}
+
+	if status, _ := do(http.MethodDelete, ""); status != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: expected 405, got %d", status)
+	}
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	defer SetLevel(slog.LevelInfo)
+	SetLevel(slog.LevelWarn)
+
+	handler := NewLevelHandler()
+	put := func(body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("PUT %s: expected 200, got %d %s", body, rec.Code, rec.Body)
+		}
+	}
+
+	put(`{"level":"debug","modules":{"myapp/db":"trace"},"ttl":"50ms"}`)
+	// A second temporary change still reverts to the level from before the first one
+	put(`{"level":"trace","ttl":"50ms"}`)
+	if GetLevel() != LevelTrace {
+		t.Fatalf("expected trace, got %v", GetLevel())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for GetLevel() != slog.LevelWarn || len(ModuleLevels()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected levels to revert, got %v %v", GetLevel(), ModuleLevels())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestResetCancelsLevelHandlerTTL(t *testing.T) {
+	defer Reset()
+	SetModuleLevel("myapp/db", slog.LevelWarn)
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"modules":{"myapp/db":"trace"},"ttl":"20ms"}`)
+	NewLevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/debug/loglevel", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body)
+	}
+
+	// Reset clears the override, the pending revert must not bring anything back
+	Reset()
+	SetModuleLevel("myapp/api", slog.LevelDebug)
+	time.Sleep(60 * time.Millisecond)
+	if levels := ModuleLevels(); len(levels) != 1 || levels["myapp/api"] != slog.LevelDebug {
+		t.Errorf("expected only the new override, got %v", levels)
+	}
+}
@@ -0,0 +1,66 @@
+package mojilog
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// ColorMode controls whether the pretty handlers write ANSI colors
+type ColorMode int
+
+const (
+	// ColorAuto colors output unless the color package turned colors off,
+	// which it does when stdout is not a terminal or NO_COLOR is set (default)
+	ColorAuto ColorMode = iota
+	// ColorAlways colors output even when it goes to a file or pipe
+	ColorAlways
+	// ColorNever writes plain text
+	ColorNever
+)
+
+// String returns the mode name as accepted by ParseColorMode
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAuto:
+		return "auto"
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return fmt.Sprintf("ColorMode(%d)", int(m))
+	}
+}
+
+// ParseColorMode converts "auto", "always" or "never" to a ColorMode
+func ParseColorMode(s string) (ColorMode, error) {
+	for _, m := range []ColorMode{ColorAuto, ColorAlways, ColorNever} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return ColorAuto, fmt.Errorf("unknown color mode %q (want auto, always or never)", s)
+}
+
+// forcedColors caches a copy of each color with colors forced on, by the original
+var forcedColors sync.Map
+
+// paint colors s with c according to mode
+func paint(mode ColorMode, c *color.Color, s string) string {
+	switch mode {
+	case ColorNever:
+		return s
+	case ColorAlways:
+		forced, ok := forcedColors.Load(c)
+		if !ok {
+			f := *c
+			f.EnableColor()
+			forced, _ = forcedColors.LoadOrStore(c, &f)
+		}
+		return forced.(*color.Color).Sprint(s)
+	default:
+		return c.Sprint(s)
+	}
+}
@@ -0,0 +1,183 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSlogtestConformance(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler func(buf *bytes.Buffer) slog.Handler
+		parse   func(t *testing.T, data []byte) []map[string]any
+	}{
+		{
+			name: "EmojiHandler text",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return NewEmojiHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			},
+			parse: parseLogfmt,
+		},
+		{
+			name: "EmojiHandler json",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return NewEmojiHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			},
+			parse: parseJSONLines,
+		},
+		{
+			name: "EmojiHandler attr",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				h := NewEmojiHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+				h.SetPlacement(EmojiAttr)
+				return h
+			},
+			parse: parseJSONLines,
+		},
+		{
+			name: "ContextHandler json",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return NewContextHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}), staticExtractor)
+			},
+			parse: parseJSONLines,
+		},
+		{
+			name:    "New pretty",
+			handler: newConformanceHandler(FormatPretty),
+			parse: func(t *testing.T, data []byte) []map[string]any {
+				var results []map[string]any
+				for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+					results = append(results, parsePrettyLine(t, line))
+				}
+				return results
+			},
+		},
+		{
+			name:    "New pretty-json",
+			handler: newConformanceHandler(FormatPrettyJSON),
+			parse:   parsePrettyJSON,
+		},
+		{
+			name:    "New json",
+			handler: newConformanceHandler(FormatJSON),
+			parse:   parseJSONLines,
+		},
+		{
+			name:    "New logfmt",
+			handler: newConformanceHandler(FormatLogfmt),
+			parse:   parseLogfmt,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := slogtest.TestHandler(tc.handler(&buf), func() []map[string]any {
+				return tc.parse(t, buf.Bytes())
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// newConformanceHandler builds a handler with New whose output the parsers understand.
+// It includes the module level, context and fan-out handlers.
+func newConformanceHandler(format Format) func(buf *bytes.Buffer) slog.Handler {
+	return func(buf *bytes.Buffer) slog.Handler {
+		return NewHandler(
+			WithWriter(buf),
+			WithFormat(format),
+			WithLevel(slog.LevelDebug),
+			WithColor(ColorNever),
+			WithTimeFormat(time.RFC3339Nano),
+			WithSkipKeys(),
+			WithEmojiPlacement(EmojiOff),
+			WithContextExtractors(staticExtractor),
+			WithHandlers(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		)
+	}
+}
+
+// staticExtractor adds the same top-level attribute to every record, so the
+// conformance checks cover grouped records with context attributes
+func staticExtractor(context.Context) []slog.Attr {
+	return []slog.Attr{slog.String("static", "yes")}
+}
+
+// parseJSONLines decodes one JSON object per line, removing the emoji from messages
+func parseJSONLines(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var results []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		var m map[string]any
+		if err := json.Unmarshal(line, &m); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if msg, ok := m[slog.MessageKey].(string); ok {
+			m[slog.MessageKey] = stripEmoji(msg)
+		}
+		results = append(results, m)
+	}
+	return results
+}
+
+// parseLogfmt parses slog's text output, one record per line. Dotted keys
+// become nested maps and the emoji is removed from messages.
+func parseLogfmt(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var results []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		m := make(map[string]any)
+		for _, token := range splitPrettyLine(line) {
+			key, value, ok := strings.Cut(token, "=")
+			if !ok {
+				t.Fatalf("bad pair %q in %q", token, line)
+			}
+			if strings.HasPrefix(value, `"`) {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					t.Fatalf("bad quoted value in %q: %v", line, err)
+				}
+				value = unquoted
+			}
+			if key == slog.MessageKey {
+				value = stripEmoji(value)
+			}
+
+			group := m
+			path := strings.Split(key, ".")
+			for _, name := range path[:len(path)-1] {
+				sub, ok := group[name].(map[string]any)
+				if !ok {
+					sub = make(map[string]any)
+					group[name] = sub
+				}
+				group = sub
+			}
+			group[path[len(path)-1]] = value
+		}
+		results = append(results, m)
+	}
+	return results
+}
+
+// stripEmoji removes a leading glyph and its spacing from a message
+func stripEmoji(msg string) string {
+	if r, _ := utf8.DecodeRuneInString(msg); r < utf8.RuneSelf {
+		return msg
+	}
+	if i := strings.IndexByte(msg, ' '); i != -1 {
+		return strings.TrimLeft(msg[i:], " ")
+	}
+	return msg
+}
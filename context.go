@@ -0,0 +1,25 @@
+package mojilog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// loggerKey is the context key of the logger stored by NewContext
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, e.g. one with request
+// attributes from With. The global *Context functions log through it.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored by NewContext, or the global logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return Get()
+}
@@ -0,0 +1,66 @@
+package mojilog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ContextExtractor returns attributes to add to a record from its context,
+// e.g. a tenant ID stored under your own key
+type ContextExtractor func(ctx context.Context) []slog.Attr
+
+// ContextHandler wraps another handler and adds the attributes returned by
+// its extractors to every record. They stay at the top level, outside any
+// groups opened with WithGroup, so backends can correlate on e.g. trace_id.
+type ContextHandler struct {
+	wrapped    slog.Handler
+	extractors []ContextExtractor
+}
+
+// NewContextHandler creates a handler adding the attributes of the extractors to the given handler
+func NewContextHandler(wrapped slog.Handler, extractors ...ContextExtractor) *ContextHandler {
+	return &ContextHandler{wrapped: topLevel(wrapped), extractors: extractors}
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *ContextHandler) handlesTopAttrs() bool { return true }
+
+// Enabled implements slog.Handler
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.wrapped.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	var extracted []slog.Attr
+	for _, extract := range h.extractors {
+		extracted = append(extracted, extract(ctx)...)
+	}
+	if len(extracted) > 0 {
+		// Record attributes would land in the groups, the wrapped handler
+		// puts these at the top level
+		ctx = withTopAttrs(ctx, extracted...)
+	}
+	return h.wrapped.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{wrapped: h.wrapped.WithAttrs(attrs), extractors: h.extractors}
+}
+
+// WithGroup implements slog.Handler
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{wrapped: h.wrapped.WithGroup(name), extractors: h.extractors}
+}
+
+// ContextValue returns an extractor adding the value stored in the context
+// under ctxKey as key, if there is one
+func ContextValue(key string, ctxKey any) ContextExtractor {
+	return func(ctx context.Context) []slog.Attr {
+		if v := ctx.Value(ctxKey); v != nil {
+			return []slog.Attr{slog.Any(key, v)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestContextHandler(t *testing.T) {
+	userID := func(ctx context.Context) []slog.Attr {
+		if id, ok := ctx.Value(ctxKey{}).(string); ok {
+			return []slog.Attr{slog.String("user_id", id)}
+		}
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = context.WithValue(ctx, ctxKey{}, "u42")
+
+	testCases := []struct {
+		format   Format
+		expected []string
+	}{
+		{FormatPretty, []string{"order placed tenant=acme user_id=u42"}},
+		{FormatPrettyJSON, []string{`"tenant": "acme"`, `"user_id": "u42"`}},
+		{FormatJSON, []string{`"tenant":"acme","user_id":"u42"`}},
+	}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		logger := New(
+			WithWriter(&buf),
+			WithFormat(tc.format),
+			WithColor(ColorNever),
+			WithContextExtractors(ContextValue("tenant", tenantKey{}), userID),
+		)
+		logger.InfoContext(ctx, "order placed")
+		logger.Info("no context values")
+
+		out := buf.String()
+		for _, want := range tc.expected {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: expected %s in %s", tc.format, want, out)
+			}
+		}
+		if strings.Count(out, "acme") != 1 {
+			t.Errorf("%s: expected only the first record to be enriched, got %s", tc.format, out)
+		}
+	}
+}
+
+func TestContextHandlerGroupedConcurrent(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	for _, format := range []Format{FormatPretty, FormatPrettyJSON, FormatJSON} {
+		var buf bytes.Buffer
+		logger := New(
+			WithWriter(&buf),
+			WithFormat(format),
+			WithColor(ColorNever),
+			WithContextExtractors(ContextValue("tenant", tenantKey{})),
+		).With("service", "shop").WithGroup("http")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					logger.InfoContext(ctx, "request done", "status", 200)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := strings.Count(buf.String(), "acme"); n != 400 {
+			t.Errorf("%s: expected 400 records with the tenant, got %d", format, n)
+		}
+	}
+}
+
+func BenchmarkContextHandler(b *testing.B) {
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	for _, format := range []Format{FormatPretty, FormatJSON} {
+		for _, grouped := range []bool{false, true} {
+			logger := New(
+				WithWriter(io.Discard),
+				WithFormat(format),
+				WithContextExtractors(ContextValue("tenant", tenantKey{})),
+			).With("service", "shop", "method", "GET")
+			name := string(format)
+			if grouped {
+				logger = logger.WithGroup("http")
+				name += "/grouped"
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					logger.InfoContext(ctx, "request done", "status", 200)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package mojilog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recordingHandler keeps the records and contexts it handles
+type recordingHandler struct {
+	records  []slog.Record
+	contexts []context.Context
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	h.contexts = append(h.contexts, ctx)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+type ctxKey struct{}
+
+func TestContextFunctions(t *testing.T) {
+	defer Reset()
+	rec := &recordingHandler{}
+	Configure(WithWriter(io.Discard), WithLevel(LevelTrace), WithHandlers(rec))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+	DebugContext(ctx, "debug")
+	InfoContext(ctx, "info")
+	WarnContext(ctx, "warn")
+	ErrorContext(ctx, "error")
+	Log(ctx, LevelNotice, "notice")
+	LogAttrs(ctx, LevelCritical, "critical", slog.String("k", "v"))
+
+	if len(rec.records) != 6 {
+		t.Fatalf("expected 6 records, got %d", len(rec.records))
+	}
+	for i, r := range rec.records {
+		if rec.contexts[i].Value(ctxKey{}) != "req-1" {
+			t.Errorf("%s: context not passed through", r.Message)
+		}
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if !strings.HasSuffix(f.Function, ".TestContextFunctions") {
+			t.Errorf("%s: expected the caller to be the test, got %s", r.Message, f.Function)
+		}
+	}
+	if rec.records[4].Level != LevelNotice || rec.records[5].NumAttrs() != 1 {
+		t.Error("expected Log and LogAttrs to keep level and attributes")
+	}
+}
+
+func TestLoggerInContext(t *testing.T) {
+	defer Reset()
+	global, scoped := &recordingHandler{}, &recordingHandler{}
+	Configure(WithWriter(io.Discard), WithHandlers(global))
+
+	if FromContext(context.Background()) != Get() {
+		t.Error("expected FromContext to fall back to the global logger")
+	}
+
+	ctx := NewContext(context.Background(), slog.New(scoped).With("request_id", "r1"))
+	InfoContext(ctx, "scoped")
+	Info("global")
+
+	if len(scoped.records) != 1 || scoped.records[0].Message != "scoped" {
+		t.Errorf("expected the context logger to get the scoped record, got %v", scoped.records)
+	}
+	if len(global.records) != 1 || global.records[0].Message != "global" {
+		t.Errorf("expected the global logger to get the other record, got %v", global.records)
+	}
+	f, _ := runtime.CallersFrames([]uintptr{scoped.records[0].PC}).Next()
+	if !strings.HasSuffix(f.Function, ".TestLoggerInContext") {
+		t.Errorf("expected the caller to be the test, got %s", f.Function)
+	}
+}
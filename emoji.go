@@ -2,22 +2,100 @@ package mojilog
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 
 	"github.com/mattn/go-runewidth"
 )
 
+// EmojiPlacement controls where EmojiHandler puts the emoji
+type EmojiPlacement int
+
+const (
+	// EmojiPrefix prepends the emoji to the message (default)
+	EmojiPrefix EmojiPlacement = iota
+	// EmojiAttr adds the emoji as a separate top-level attribute, outside any groups,
+	// and leaves the message untouched
+	EmojiAttr
+	// EmojiSuffix appends the emoji to the message
+	EmojiSuffix
+	// EmojiOff disables emojis
+	EmojiOff
+)
+
+// DefaultEmojiKey is the attribute key used by EmojiAttr placement
+const DefaultEmojiKey = "emoji"
+
+// String returns the placement name as accepted by ParseEmojiPlacement
+func (p EmojiPlacement) String() string {
+	switch p {
+	case EmojiPrefix:
+		return "prefix"
+	case EmojiAttr:
+		return "attr"
+	case EmojiSuffix:
+		return "suffix"
+	case EmojiOff:
+		return "off"
+	default:
+		return fmt.Sprintf("EmojiPlacement(%d)", int(p))
+	}
+}
+
+// ParseEmojiPlacement converts "prefix", "attr", "suffix" or "off" to an EmojiPlacement
+func ParseEmojiPlacement(s string) (EmojiPlacement, error) {
+	for _, p := range []EmojiPlacement{EmojiPrefix, EmojiAttr, EmojiSuffix, EmojiOff} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return EmojiPrefix, fmt.Errorf("unknown emoji placement %q (want prefix, attr, suffix or off)", s)
+}
+
 // EmojiHandler wraps another handler and adds emojis based on log level
 type EmojiHandler struct {
-	wrapped slog.Handler
+	wrapped   slog.Handler
+	rules     *EmojiRuleSet
+	theme     *Theme
+	placement EmojiPlacement
+	key       string
+	attrs     []slog.Attr // qualified with the groups open when they were added
+	groups    []string
 }
 
 // NewEmojiHandler creates a new emoji handler that wraps the given handler
 func NewEmojiHandler(wrapped slog.Handler) *EmojiHandler {
-	return &EmojiHandler{wrapped: wrapped}
+	return &EmojiHandler{
+		wrapped: topLevel(wrapped),
+		rules:   DefaultEmojiRules(),
+		key:     DefaultEmojiKey,
+	}
+}
+
+// SetEmojiRules sets the rules used to pick contextual emojis, nil disables them
+func (h *EmojiHandler) SetEmojiRules(rules *EmojiRuleSet) {
+	h.rules = rules
+}
+
+// SetTheme sets the glyph theme, nil follows DefaultTheme
+func (h *EmojiHandler) SetTheme(theme *Theme) {
+	h.theme = theme
 }
 
+// SetPlacement sets where the emoji goes, see EmojiPlacement
+func (h *EmojiHandler) SetPlacement(placement EmojiPlacement) {
+	h.placement = placement
+}
+
+// SetEmojiKey sets the attribute key used by EmojiAttr placement
+func (h *EmojiHandler) SetEmojiKey(key string) {
+	h.key = key
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *EmojiHandler) handlesTopAttrs() bool { return true }
+
 // Enabled implements slog.Handler
 func (h *EmojiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return h.wrapped.Enabled(ctx, level)
@@ -25,20 +103,25 @@ func (h *EmojiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 
 // Handle implements slog.Handler
 func (h *EmojiHandler) Handle(ctx context.Context, r slog.Record) error {
-	// Get contextual emoji first (higher priority)
-	contextEmoji := getContextualEmoji(r.Message)
+	if h.placement == EmojiOff {
+		return h.wrapped.Handle(ctx, r)
+	}
 
 	// Use contextual emoji if available, otherwise use level emoji
-	emoji := ""
-	if contextEmoji != "" {
-		emoji = contextEmoji
-	} else {
-		emoji = getEmojiForLevel(r.Level)
+	emoji := pickEmoji(h.theme, h.rules, withHandlerAttrs(topAttrs(ctx), h.attrs), h.groups, r)
+	if emoji == "" {
+		return h.wrapped.Handle(ctx, r)
 	}
 
-	// Prepend emoji to the message with appropriate spacing
-	// if the emoji takes up double-space like ⚙️, add a double space,and if emoji takes up single-space like 🚀, then add a single space
-	if emoji != "" {
+	switch h.placement {
+	case EmojiAttr:
+		// The attribute stays at the top level so alerting can match on it
+		ctx = withTopAttrs(ctx, slog.String(h.key, emoji))
+	case EmojiSuffix:
+		r.Message = r.Message + " " + emoji
+	default:
+		// Prepend emoji to the message with appropriate spacing
+		// if the emoji takes up double-space like ⚙️, add a double space,and if emoji takes up single-space like 🚀, then add a single space
 		spacing := getEmojiSpacing(emoji)
 		r.Message = emoji + spacing + r.Message
 	}
@@ -48,28 +131,24 @@ func (h *EmojiHandler) Handle(ctx context.Context, r slog.Record) error {
 
 // WithAttrs implements slog.Handler
 func (h *EmojiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &EmojiHandler{wrapped: h.wrapped.WithAttrs(attrs)}
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.wrapped = h.wrapped.WithAttrs(attrs)
+	h2.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], qualifyAttrs(h.groups, attrs)...)
+	return &h2
 }
 
 // WithGroup implements slog.Handler
 func (h *EmojiHandler) WithGroup(name string) slog.Handler {
-	return &EmojiHandler{wrapped: h.wrapped.WithGroup(name)}
-}
-
-// getEmojiForLevel returns an emoji based on the log level
-func getEmojiForLevel(level slog.Level) string {
-	switch {
-	case level >= slog.LevelError:
-		return "❌"
-	case level >= slog.LevelWarn:
-		return "⚠️"
-	case level >= slog.LevelInfo:
-		return "ℹ️"
-	case level >= slog.LevelDebug:
-		return "🔍"
-	default:
-		return "📝"
+	if name == "" {
+		return h
 	}
+	h2 := *h
+	h2.wrapped = h.wrapped.WithGroup(name)
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
 }
 
 // getEmojiSpacing returns appropriate spacing based on emoji display width
@@ -85,71 +164,6 @@ func getEmojiSpacing(emoji string) string {
 	return "  " // single space for narrow emojis
 }
 
-// getContextualEmoji returns a context-specific emoji based on message content
-func getContextualEmoji(msg string) string {
-	lowerMsg := toLower(msg)
-
-	// Health status patterns - highest priority
-	if stringContains(lowerMsg, "health") {
-		if stringContains(lowerMsg, "excellent") {
-			return "💚"
-		} else if stringContains(lowerMsg, "good") {
-			return "🟡"
-		} else if stringContains(lowerMsg, "degraded") {
-			return "🟠"
-		} else if stringContains(lowerMsg, "critical") {
-			return "🔴"
-		}
-	}
-
-	// System states - check for more specific patterns first
-	if stringContains(lowerMsg, "shutdown") || stringContains(lowerMsg, "stopping") {
-		return "🛑"
-	}
-	// Only use rocket for main application start, not sub-components
-	if stringContains(lowerMsg, "start") || stringContains(lowerMsg, "parser is running") {
-		return "🚀"
-	}
-
-	if stringContains(lowerMsg, "metrics") {
-		return "📊"
-	}
-
-	if stringContains(lowerMsg, "success") {
-		return "🎉"
-	}
-
-	if stringContains(lowerMsg, "cleanup") {
-		return "🧹"
-	}
-
-	// Operations
-	if stringContains(lowerMsg, "config") || stringContains(lowerMsg, "setting") {
-		return "⚙️"
-	}
-	if stringContains(lowerMsg, "connect") || stringContains(lowerMsg, "websocket") {
-		return "🔌"
-	}
-	if stringContains(lowerMsg, "failed") {
-		return "❌"
-	}
-	if stringContains(lowerMsg, "table") || stringContains(lowerMsg, "game") || stringContains(lowerMsg, "casino") {
-		return "🎰"
-	}
-	if stringContains(lowerMsg, "statistics") || stringContains(lowerMsg, "metrics") {
-		return "📊"
-	}
-	if stringContains(lowerMsg, "loading") || stringContains(lowerMsg, "processing") {
-		return "⏳"
-	}
-
-	if stringContains(lowerMsg, "creating") {
-		return "🆕"
-	}
-
-	return ""
-}
-
 // Simple lowercase conversion
 func toLower(s string) string {
 	result := make([]byte, len(s))
@@ -164,35 +178,12 @@ func toLower(s string) string {
 	return string(result)
 }
 
-// Simple contains check
-func stringContains(s, substr string) bool {
-	if len(substr) > len(s) {
-		return false
-	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
-
-// SetupLogger sets up a global logger with emoji support
+// SetupLogger sets up a logger with emoji support, format is "json" or anything else
+// for slog's text output. The level follows SetLevel once it is called. See New for more options.
 func SetupLogger(w io.Writer, level slog.Level, format string, addSource bool) *slog.Logger {
-	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: addSource,
-	}
-
-	var baseHandler slog.Handler
+	f := FormatLogfmt
 	if format == "json" {
-		baseHandler = slog.NewJSONHandler(w, opts)
-	} else {
-		baseHandler = slog.NewTextHandler(w, opts)
+		f = FormatJSON
 	}
-
-	// Wrap with emoji handler
-	emojiHandler := NewEmojiHandler(baseHandler)
-
-	return slog.New(emojiHandler)
+	return New(WithWriter(w), WithLevel(newSetupLevel(level)), WithFormat(f), WithSource(addSource))
 }
@@ -0,0 +1,149 @@
+package mojilog
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// attrCondition is a parsed attribute condition of an emoji rule
+type attrCondition struct {
+	key   string
+	op    string
+	value string
+}
+
+// conditionOps lists the supported operators, longest first so ">=" wins over ">"
+var conditionOps = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// parseAttrCondition parses conditions such as "error", "component=db" or "http.status>=500"
+func parseAttrCondition(s string) (attrCondition, error) {
+	cond := attrCondition{key: strings.TrimSpace(s)}
+	for i := 0; i < len(s); i++ {
+		for _, op := range conditionOps {
+			if strings.HasPrefix(s[i:], op) {
+				cond = attrCondition{
+					key:   strings.TrimSpace(s[:i]),
+					op:    op,
+					value: strings.TrimSpace(s[i+len(op):]),
+				}
+				i = len(s)
+				break
+			}
+		}
+	}
+	if cond.key == "" {
+		return cond, fmt.Errorf("invalid attribute condition %q: missing key", s)
+	}
+	return cond, nil
+}
+
+// matches reports whether the condition holds for the attributes
+func (c attrCondition) matches(attrs []slog.Attr) bool {
+	v, ok := lookupAttr(attrs, c.key)
+	if !ok {
+		return false
+	}
+	if c.op == "" {
+		return true
+	}
+
+	if cmp, ok := compareValue(v, c.value); ok {
+		switch c.op {
+		case "=":
+			return cmp == 0
+		case "!=":
+			return cmp != 0
+		case ">":
+			return cmp > 0
+		case ">=":
+			return cmp >= 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		}
+	}
+
+	// Fall back to string equality for values that can't be ordered
+	switch c.op {
+	case "=":
+		return v.String() == c.value
+	case "!=":
+		return v.String() != c.value
+	}
+	return false
+}
+
+// compareValue compares an attribute value with a condition operand,
+// ok is false when they can't be compared numerically
+func compareValue(v slog.Value, operand string) (int, bool) {
+	var got, want float64
+	switch v.Kind() {
+	case slog.KindInt64:
+		got = float64(v.Int64())
+	case slog.KindUint64:
+		got = float64(v.Uint64())
+	case slog.KindFloat64:
+		got = v.Float64()
+	case slog.KindDuration:
+		d, err := time.ParseDuration(operand)
+		if err != nil {
+			return 0, false
+		}
+		got, want = float64(v.Duration()), float64(d)
+		return compareFloat(got, want), true
+	case slog.KindString:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return 0, false
+		}
+		got = f
+	default:
+		return 0, false
+	}
+
+	want, err := strconv.ParseFloat(operand, 64)
+	if err != nil {
+		return 0, false
+	}
+	return compareFloat(got, want), true
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lookupAttr finds an attribute by its dotted key, descending into groups
+func lookupAttr(attrs []slog.Attr, key string) (slog.Value, bool) {
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if a.Key == key {
+			return v, true
+		}
+		if v.Kind() != slog.KindGroup {
+			continue
+		}
+		if a.Key == "" {
+			if found, ok := lookupAttr(v.Group(), key); ok {
+				return found, true
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(key, a.Key+"."); ok {
+			if found, ok := lookupAttr(v.Group(), rest); ok {
+				return found, true
+			}
+		}
+	}
+	return slog.Value{}, false
+}
@@ -0,0 +1,302 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleFileEntry is a single rule as written in an emoji rule file
+type ruleFileEntry struct {
+	Name     string   `json:"name" yaml:"name"`
+	Emoji    string   `json:"emoji" yaml:"emoji"`
+	Category string   `json:"category" yaml:"category"`
+	Keywords []string `json:"keywords" yaml:"keywords"`
+	Requires []string `json:"requires" yaml:"requires"`
+	Attrs    []string `json:"attrs" yaml:"attrs"`
+}
+
+var ruleFileKeys = map[string]bool{
+	"name":     true,
+	"emoji":    true,
+	"category": true,
+	"keywords": true,
+	"requires": true,
+	"attrs":    true,
+}
+
+func (e ruleFileEntry) rule() EmojiRule {
+	return EmojiRule{
+		Name:     e.Name,
+		Emoji:    e.Emoji,
+		Category: Category(e.Category),
+		Keywords: e.Keywords,
+		Requires: e.Requires,
+		Attrs:    e.Attrs,
+	}
+}
+
+// RuleFileError reports a problem in an emoji rule file
+type RuleFileError struct {
+	Path string
+	Line int // 0 when the problem isn't tied to a line
+	Err  error
+}
+
+func (e *RuleFileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *RuleFileError) Unwrap() error {
+	return e.Err
+}
+
+// ReadEmojiRules reads and validates the rules of a JSON or YAML rule file.
+// The format is picked from the extension (.json, .yaml or .yml), e.g.
+//
+//	{"rules": [{"name": "deploy", "emoji": "🚢", "keywords": ["deploy"]}]}
+//
+// or
+//
+//	rules:
+//	  - name: server-error
+//	    emoji: 🔥
+//	    attrs: ["http.status>=500"]
+func ReadEmojiRules(path string) ([]EmojiRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSONRules(path, data)
+	case ".yaml", ".yml":
+		return parseYAMLRules(path, data)
+	default:
+		return nil, &RuleFileError{Path: path, Err: fmt.Errorf("unsupported rule file extension %q", filepath.Ext(path))}
+	}
+}
+
+// LoadEmojiRules builds a rule set from a JSON or YAML rule file
+func LoadEmojiRules(path string) (*EmojiRuleSet, error) {
+	rules, err := ReadEmojiRules(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEmojiRuleSet(rules...)
+}
+
+// ReloadEmojiRules replaces the rules of set with those in the file.
+// If the file is invalid the set keeps its current rules.
+func ReloadEmojiRules(set *EmojiRuleSet, path string) error {
+	rules, err := ReadEmojiRules(path)
+	if err != nil {
+		return err
+	}
+	return set.SetRules(rules...)
+}
+
+// WatchEmojiRules polls the rule file every interval in the background and
+// reloads set when the file changes, until ctx is done. Reload errors are passed
+// to onError (if not nil) and leave the previous rules in place. A missing file,
+// e.g. while an editor saves by renaming, is reported once until it is back.
+func WatchEmojiRules(ctx context.Context, set *EmojiRuleSet, path string, interval time.Duration, onError func(error)) {
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+
+	var lastMod time.Time
+	var lastSize int64
+	var statErr string // the last reported stat error, "" while the file is there
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				if statErr != err.Error() {
+					statErr = err.Error()
+					report(err)
+				}
+				continue
+			}
+			statErr = ""
+			if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = info.ModTime(), info.Size()
+
+			if err := ReloadEmojiRules(set, path); err != nil {
+				report(err)
+			}
+		}
+	}()
+}
+
+// parseJSONRules decodes the rules one at a time so errors can point at the rule's line
+func parseJSONRules(path string, data []byte) ([]EmojiRule, error) {
+	fail := func(offset int64, err error) error {
+		return &RuleFileError{Path: path, Line: lineAt(data, offset), Err: err}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, fail(dec.InputOffset(), err)
+	}
+
+	var rules []EmojiRule
+	seen := make(map[string]int)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fail(dec.InputOffset(), err)
+		}
+		if tok != "rules" {
+			return nil, fail(dec.InputOffset(), fmt.Errorf("unknown field %v", tok))
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return nil, fail(dec.InputOffset(), err)
+		}
+
+		for dec.More() {
+			line := lineAt(data, dec.InputOffset())
+
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, &RuleFileError{Path: path, Line: line, Err: err}
+			}
+			var entry ruleFileEntry
+			entryDec := json.NewDecoder(bytes.NewReader(raw))
+			entryDec.DisallowUnknownFields()
+			if err := entryDec.Decode(&entry); err != nil {
+				return nil, &RuleFileError{Path: path, Line: line, Err: err}
+			}
+
+			rule, err := checkRule(entry.rule(), line, seen)
+			if err != nil {
+				return nil, &RuleFileError{Path: path, Line: line, Err: err}
+			}
+			rules = append(rules, rule)
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return nil, fail(dec.InputOffset(), err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, fail(dec.InputOffset(), err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fail(dec.InputOffset(), errors.New("unexpected data after rules"))
+	}
+	return rules, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// lineAt returns the line of the first non-space byte at or after offset
+func lineAt(data []byte, offset int64) int {
+	for int(offset) < len(data) && strings.ContainsRune(" \t\r\n,:", rune(data[offset])) {
+		offset++
+	}
+	if int(offset) > len(data) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func parseYAMLRules(path string, data []byte) ([]EmojiRule, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &RuleFileError{Path: path, Err: err}
+	}
+	if len(doc.Content) == 0 {
+		// Editors often truncate a file before writing it, don't take that for "no rules"
+		return nil, &RuleFileError{Path: path, Err: errors.New("empty rule file")}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, &RuleFileError{Path: path, Line: root.Line, Err: errors.New("expected a mapping with a rules list")}
+	}
+
+	var rules []EmojiRule
+	seen := make(map[string]int)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "rules" {
+			return nil, &RuleFileError{Path: path, Line: key.Line, Err: fmt.Errorf("unknown field %q", key.Value)}
+		}
+		if value.Kind != yaml.SequenceNode {
+			return nil, &RuleFileError{Path: path, Line: value.Line, Err: errors.New("rules must be a list")}
+		}
+
+		for _, node := range value.Content {
+			if node.Kind != yaml.MappingNode {
+				return nil, &RuleFileError{Path: path, Line: node.Line, Err: errors.New("rule must be a mapping")}
+			}
+			for j := 0; j < len(node.Content); j += 2 {
+				if k := node.Content[j]; !ruleFileKeys[k.Value] {
+					return nil, &RuleFileError{Path: path, Line: k.Line, Err: fmt.Errorf("unknown field %q", k.Value)}
+				}
+			}
+
+			var entry ruleFileEntry
+			if err := node.Decode(&entry); err != nil {
+				return nil, &RuleFileError{Path: path, Line: node.Line, Err: err}
+			}
+			rule, err := checkRule(entry.rule(), node.Line, seen)
+			if err != nil {
+				return nil, &RuleFileError{Path: path, Line: node.Line, Err: err}
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// checkRule validates a rule from a file and rejects names used on an earlier line
+func checkRule(rule EmojiRule, line int, seen map[string]int) (EmojiRule, error) {
+	if err := rule.Validate(); err != nil {
+		return rule, err
+	}
+	if prev, ok := seen[rule.Name]; ok {
+		return rule, fmt.Errorf("emoji rule %q: already defined on line %d", rule.Name, prev)
+	}
+	seen[rule.Name] = line
+	return rule, nil
+}
@@ -0,0 +1,202 @@
+package mojilog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRuleFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEmojiRules(t *testing.T) {
+	files := map[string]string{
+		"rules.json": `{
+  "rules": [
+    {"name": "deploy", "emoji": "🚢", "keywords": ["deploy"]},
+    {"name": "server-error", "emoji": "🔥", "attrs": ["status>=500"]}
+  ]
+}`,
+		"rules.yaml": `rules:
+  - name: deploy
+    emoji: 🚢
+    keywords: [deploy]
+  - name: server-error
+    emoji: 🔥
+    attrs: ["status>=500"]
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			rules, err := LoadEmojiRules(writeRuleFile(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rules.Match("Deploy finished", nil); got != "🚢" {
+				t.Errorf("expected 🚢, got %q", got)
+			}
+			if got := rules.Match("request done", []slog.Attr{slog.Int("status", 502)}); got != "🔥" {
+				t.Errorf("expected 🔥, got %q", got)
+			}
+		})
+	}
+}
+
+func TestLoadEmojiRulesReportsLine(t *testing.T) {
+	files := map[string]string{
+		"rules.json": `{
+  "rules": [
+    {"name": "deploy", "emoji": "🚢", "keywords": ["deploy"]},
+    {"name": "broken", "emoji": "🔥", "attrs": [">=500"]}
+  ]
+}`,
+		"rules.yml": `rules:
+  - name: deploy
+    emoji: 🚢
+    keywords: [deploy]
+  - name: deploy
+    emoji: 🚀
+    keywords: [release]
+`,
+	}
+	expectedLines := map[string]int{"rules.json": 4, "rules.yml": 5}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadEmojiRules(writeRuleFile(t, name, content))
+			var fileErr *RuleFileError
+			if !errors.As(err, &fileErr) {
+				t.Fatalf("expected RuleFileError, got %v", err)
+			}
+			if fileErr.Line != expectedLines[name] {
+				t.Errorf("expected line %d, got %d (%v)", expectedLines[name], fileErr.Line, err)
+			}
+		})
+	}
+}
+
+func TestWatchEmojiRules(t *testing.T) {
+	path := writeRuleFile(t, "rules.json", `{"rules": [{"name": "a", "emoji": "🅰️", "keywords": ["alpha"]}]}`)
+	rules, err := LoadEmojiRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	WatchEmojiRules(ctx, rules, path, 5*time.Millisecond, func(err error) { errs <- err })
+
+	update := func(content string, mod time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An invalid file is reported and the old rules stay active
+	update(`{"rules": [{"name": "b"}]}`, time.Now().Add(time.Second))
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("invalid rule file was not reported")
+	}
+	if got := rules.Match("alpha", nil); got != "🅰️" {
+		t.Fatalf("expected old rules to stay active, got %q", got)
+	}
+
+	update(`{"rules": [{"name": "b", "emoji": "🅱️", "keywords": ["beta"]}]}`, time.Now().Add(2*time.Second))
+	deadline := time.Now().Add(time.Second)
+	for rules.Match("beta", nil) != "🅱️" {
+		if time.Now().After(deadline) {
+			t.Fatal("rules were not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatchEmojiRulesEmptyFile(t *testing.T) {
+	path := writeRuleFile(t, "rules.yaml", "rules:\n  - name: a\n    emoji: 🅰️\n    keywords: [alpha]\n")
+	rules, err := LoadEmojiRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	WatchEmojiRules(ctx, rules, path, 5*time.Millisecond, func(err error) { errs <- err })
+
+	// A truncated file is reported and the old rules stay active
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		var fileErr *RuleFileError
+		if !errors.As(err, &fileErr) {
+			t.Fatalf("expected RuleFileError, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("empty rule file was not reported")
+	}
+	if got := rules.Match("alpha", nil); got != "🅰️" {
+		t.Fatalf("expected old rules to stay active, got %q", got)
+	}
+}
+
+func TestWatchEmojiRulesMissingFile(t *testing.T) {
+	path := writeRuleFile(t, "rules.json", `{"rules": [{"name": "a", "emoji": "🅰️", "keywords": ["alpha"]}]}`)
+	rules, err := LoadEmojiRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 100)
+	WatchEmojiRules(ctx, rules, path, 5*time.Millisecond, func(err error) { errs <- err })
+
+	// A missing file is reported once, not on every tick
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := len(errs); n != 1 {
+		t.Fatalf("expected the missing file to be reported once, got %d errors", n)
+	}
+	if got := rules.Match("alpha", nil); got != "🅰️" {
+		t.Fatalf("expected old rules to stay active, got %q", got)
+	}
+
+	// Once it is back it is reloaded
+	if err := os.WriteFile(path, []byte(`{"rules": [{"name": "b", "emoji": "🅱️", "keywords": ["beta"]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for rules.Match("beta", nil) != "🅱️" {
+		if time.Now().After(deadline) {
+			t.Fatal("rules were not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(errs); n != 1 {
+		t.Errorf("expected no more errors, got %d", n)
+	}
+}
@@ -0,0 +1,106 @@
+package mojilog
+
+// keywordMatcher finds all keywords in a message in a single pass, using an
+// Aho-Corasick automaton compiled into a DFA over byte classes. Matching is
+// ASCII case-insensitive and does not allocate.
+type keywordMatcher struct {
+	classes    [256]uint16 // byte -> class, class 0 is "any byte not in a keyword"
+	numClasses int
+	next       []int32 // state*numClasses + class -> state
+	outputs    [][]int // state -> keywords ending at this state
+	numWords   int
+}
+
+// smallMatchWords is the bitset size that lives on the stack during matching
+const smallMatchWords = 4
+
+// newKeywordMatcher compiles the lowercased keywords, keyword i is reported as bit i
+func newKeywordMatcher(words []string) *keywordMatcher {
+	m := &keywordMatcher{numWords: len(words)}
+
+	// Give every byte used in a keyword its own class
+	m.numClasses = 1
+	for _, w := range words {
+		for i := 0; i < len(w); i++ {
+			if m.classes[w[i]] == 0 {
+				m.classes[w[i]] = uint16(m.numClasses)
+				m.numClasses++
+			}
+		}
+	}
+
+	// Build the trie, -1 marks a missing edge
+	newState := func() int32 {
+		for c := 0; c < m.numClasses; c++ {
+			m.next = append(m.next, -1)
+		}
+		m.outputs = append(m.outputs, nil)
+		return int32(len(m.outputs) - 1)
+	}
+	newState()
+	for id, w := range words {
+		state := int32(0)
+		for i := 0; i < len(w); i++ {
+			edge := int(state)*m.numClasses + int(m.classes[w[i]])
+			if m.next[edge] == -1 {
+				s := newState()
+				m.next[edge] = s
+			}
+			state = m.next[edge]
+		}
+		m.outputs[state] = append(m.outputs[state], id)
+	}
+
+	// Breadth-first pass turning failure links into direct transitions
+	fail := make([]int32, len(m.outputs))
+	queue := make([]int32, 0, len(m.outputs))
+	for c := 0; c < m.numClasses; c++ {
+		if s := m.next[c]; s == -1 {
+			m.next[c] = 0
+		} else {
+			fail[s] = 0
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		m.outputs[state] = append(m.outputs[state], m.outputs[fail[state]]...)
+
+		for c := 0; c < m.numClasses; c++ {
+			edge := int(state)*m.numClasses + c
+			fallback := m.next[int(fail[state])*m.numClasses+c]
+			if s := m.next[edge]; s == -1 {
+				m.next[edge] = fallback
+			} else {
+				fail[s] = fallback
+				queue = append(queue, s)
+			}
+		}
+	}
+	return m
+}
+
+// bitsetWords returns the number of uint64 words needed to hold one bit per keyword
+func (m *keywordMatcher) bitsetWords() int {
+	return (m.numWords + 63) / 64
+}
+
+// scan sets bit i of found for every keyword i that occurs in msg
+func (m *keywordMatcher) scan(msg string, found []uint64) {
+	state := int32(0)
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		state = m.next[int(state)*m.numClasses+int(m.classes[c])]
+		for _, id := range m.outputs[state] {
+			found[id/64] |= 1 << (id % 64)
+		}
+	}
+}
+
+func hasBit(found []uint64, id int) bool {
+	return found[id/64]&(1<<(id%64)) != 0
+}
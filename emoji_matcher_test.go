@@ -0,0 +1,93 @@
+package mojilog
+
+import (
+	"io"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestKeywordMatcher(t *testing.T) {
+	words := []string{"he", "she", "his", "hers", "start", "parser is running", "art"}
+	m := newKeywordMatcher(words)
+
+	rng := rand.New(rand.NewSource(1))
+	alphabet := "hesriSTARtpnug "
+	for n := 0; n < 2000; n++ {
+		var sb strings.Builder
+		for i := rng.Intn(30); i > 0; i-- {
+			sb.WriteByte(alphabet[rng.Intn(len(alphabet))])
+		}
+		msg := sb.String()
+
+		found := make([]uint64, m.bitsetWords())
+		m.scan(msg, found)
+		for id, w := range words {
+			expected := strings.Contains(strings.ToLower(msg), w)
+			if hasBit(found, id) != expected {
+				t.Fatalf("scan(%q): keyword %q expected %v", msg, w, expected)
+			}
+		}
+	}
+}
+
+func TestKeywordMatcherManyKeywords(t *testing.T) {
+	var words []string
+	for i := 0; i < 300; i++ {
+		words = append(words, "kw"+strings.Repeat("x", i%7)+string(rune('a'+i%26))+string(rune('a'+i/26)))
+	}
+	var rules []EmojiRule
+	for i, w := range words {
+		rules = append(rules, EmojiRule{Name: w, Emoji: "🔑", Keywords: []string{w}})
+		if i == len(words)-1 {
+			rules[i].Emoji = "🏁"
+		}
+	}
+	set, err := NewEmojiRuleSet(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := set.Match("found "+strings.ToUpper(words[len(words)-1]), nil); got != "🏁" {
+		t.Errorf("expected last keyword to match, got %q", got)
+	}
+}
+
+func BenchmarkEmojiRuleSetMatch(b *testing.B) {
+	rules := DefaultEmojiRules()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rules.Match("Processing batch 42 for tenant acme with 1000 items", nil)
+	}
+}
+
+func BenchmarkEmojiHandlerHandle(b *testing.B) {
+	handler := NewEmojiHandler(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logger := slog.New(handler)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("Processing batch for tenant", "batch", i)
+	}
+}
+
+func BenchmarkEmojiHandlerAttrGrouped(b *testing.B) {
+	handler := NewEmojiHandler(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	handler.SetPlacement(EmojiAttr)
+	logger := slog.New(handler).With("service", "shop").WithGroup("batch").With("tenant", "acme")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("Processing batch for tenant", "batch", i)
+	}
+}
+
+func BenchmarkPrettyHandlerHandle(b *testing.B) {
+	handler := NewPrettyHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(handler)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("Processing batch for tenant", "batch", i)
+	}
+}
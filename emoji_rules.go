@@ -0,0 +1,399 @@
+package mojilog
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// EmojiRule maps message keywords and attribute conditions to a contextual emoji
+type EmojiRule struct {
+	// Name identifies the rule inside a rule set
+	Name string
+	// Emoji is used when the rule matches and the theme has no glyph for Category
+	Emoji string
+	// Category lets themes replace the emoji with their own glyph
+	Category Category
+	// Keywords match when any of them appears in the message (case-insensitive)
+	Keywords []string
+	// Requires lists keywords that must all appear in the message as well
+	Requires []string
+	// Attrs lists attribute conditions that must all hold, such as
+	// "error" (present), "component=db" or "http.status>=500".
+	// Grouped attributes are addressed with dotted keys.
+	Attrs []string
+
+	conds []attrCondition
+}
+
+// Validate reports whether the rule can be used for matching
+func (r EmojiRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("emoji rule: missing name")
+	}
+	if r.Emoji == "" && r.Category == "" {
+		return fmt.Errorf("emoji rule %q: missing emoji or category", r.Name)
+	}
+	if len(r.Keywords) == 0 && len(r.Requires) == 0 && len(r.Attrs) == 0 {
+		return fmt.Errorf("emoji rule %q: no keywords or attribute conditions", r.Name)
+	}
+	for _, words := range [][]string{r.Keywords, r.Requires} {
+		for _, kw := range words {
+			if kw == "" {
+				return fmt.Errorf("emoji rule %q: empty keyword", r.Name)
+			}
+		}
+	}
+	for _, cond := range r.Attrs {
+		if _, err := parseAttrCondition(cond); err != nil {
+			return fmt.Errorf("emoji rule %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// normalized returns a copy of the rule with lowercased keywords and parsed conditions.
+// The rule must have been validated.
+func (r EmojiRule) normalized() EmojiRule {
+	r.Keywords = lowerAll(r.Keywords)
+	r.Requires = lowerAll(r.Requires)
+	r.Attrs = append([]string(nil), r.Attrs...)
+	r.conds = make([]attrCondition, len(r.Attrs))
+	for i, cond := range r.Attrs {
+		r.conds[i], _ = parseAttrCondition(cond)
+	}
+	return r
+}
+
+func lowerAll(words []string) []string {
+	if words == nil {
+		return nil
+	}
+	result := make([]string, len(words))
+	for i, w := range words {
+		result[i] = toLower(w)
+	}
+	return result
+}
+
+// EmojiRuleSet is an ordered list of emoji rules, the first matching rule wins.
+// It is safe for concurrent use: changes build a new snapshot that is swapped in
+// atomically, so handlers never take a lock while matching.
+type EmojiRuleSet struct {
+	mu   sync.Mutex // serializes writers
+	snap atomic.Pointer[ruleSnapshot]
+}
+
+// ruleSnapshot is an immutable view of the rules with their keywords
+// compiled into a single matcher
+type ruleSnapshot struct {
+	rules    []EmojiRule
+	keywords [][]int // rule -> ids of its Keywords
+	requires [][]int // rule -> ids of its Requires
+	matcher  *keywordMatcher
+	useAttrs bool
+}
+
+func newRuleSnapshot(rules []EmojiRule) *ruleSnapshot {
+	snap := &ruleSnapshot{
+		rules:    rules,
+		keywords: make([][]int, len(rules)),
+		requires: make([][]int, len(rules)),
+	}
+
+	ids := make(map[string]int)
+	var words []string
+	idOf := func(w string) int {
+		id, ok := ids[w]
+		if !ok {
+			id = len(words)
+			ids[w] = id
+			words = append(words, w)
+		}
+		return id
+	}
+
+	for i := range rules {
+		for _, kw := range rules[i].Keywords {
+			snap.keywords[i] = append(snap.keywords[i], idOf(kw))
+		}
+		for _, kw := range rules[i].Requires {
+			snap.requires[i] = append(snap.requires[i], idOf(kw))
+		}
+		if len(rules[i].conds) > 0 {
+			snap.useAttrs = true
+		}
+	}
+	snap.matcher = newKeywordMatcher(words)
+	return snap
+}
+
+// find returns the first rule matching the message and attributes
+func (snap *ruleSnapshot) find(msg string, attrs []slog.Attr) (EmojiRule, bool) {
+	if snap == nil || len(snap.rules) == 0 {
+		return EmojiRule{}, false
+	}
+
+	var small [smallMatchWords]uint64
+	found := small[:]
+	if n := snap.matcher.bitsetWords(); n > smallMatchWords {
+		found = make([]uint64, n)
+	}
+	snap.matcher.scan(msg, found)
+
+	for i := range snap.rules {
+		if snap.matches(i, found, attrs) {
+			return snap.rules[i], true
+		}
+	}
+	return EmojiRule{}, false
+}
+
+// matches reports whether rule i holds for the found keywords and the attributes
+func (snap *ruleSnapshot) matches(i int, found []uint64, attrs []slog.Attr) bool {
+	for _, id := range snap.requires[i] {
+		if !hasBit(found, id) {
+			return false
+		}
+	}
+	if len(snap.keywords[i]) > 0 {
+		matched := false
+		for _, id := range snap.keywords[i] {
+			if hasBit(found, id) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, cond := range snap.rules[i].conds {
+		if !cond.matches(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewEmojiRuleSet creates a rule set from the given rules, in priority order
+func NewEmojiRuleSet(rules ...EmojiRule) (*EmojiRuleSet, error) {
+	s := &EmojiRuleSet{}
+	if err := s.SetRules(rules...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DefaultEmojiRules returns a new rule set holding the built-in keyword rules
+func DefaultEmojiRules() *EmojiRuleSet {
+	s, err := NewEmojiRuleSet(defaultEmojiRules...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// SetRules replaces all rules at once. Either every rule is valid and the new
+// list is swapped in, or an error is returned and the set is left unchanged.
+func (s *EmojiRuleSet) SetRules(rules ...EmojiRule) error {
+	normalized := make([]EmojiRule, 0, len(rules))
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if seen[rule.Name] {
+			return fmt.Errorf("emoji rule %q: already registered", rule.Name)
+		}
+		seen[rule.Name] = true
+		normalized = append(normalized, rule.normalized())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Store(newRuleSnapshot(normalized))
+	return nil
+}
+
+// Add appends a rule with the lowest priority
+func (s *EmojiRuleSet) Add(rule EmojiRule) error {
+	return s.Insert(-1, rule)
+}
+
+// Insert places a rule at the given position, a negative or out of range index appends it
+func (s *EmojiRuleSet) Insert(index int, rule EmojiRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.load()
+	if indexOf(rules, rule.Name) != -1 {
+		return fmt.Errorf("emoji rule %q: already registered", rule.Name)
+	}
+	s.snap.Store(newRuleSnapshot(insertRule(rules, index, rule.normalized())))
+	return nil
+}
+
+// Replace swaps the named rule for a new one, keeping its position
+func (s *EmojiRuleSet) Replace(name string, rule EmojiRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.load()
+	idx := indexOf(rules, name)
+	if idx == -1 {
+		return fmt.Errorf("emoji rule %q: not found", name)
+	}
+	if other := indexOf(rules, rule.Name); other != -1 && other != idx {
+		return fmt.Errorf("emoji rule %q: already registered", rule.Name)
+	}
+	rules[idx] = rule.normalized()
+	s.snap.Store(newRuleSnapshot(rules))
+	return nil
+}
+
+// Remove deletes the named rule and reports whether it existed
+func (s *EmojiRuleSet) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.load()
+	idx := indexOf(rules, name)
+	if idx == -1 {
+		return false
+	}
+	s.snap.Store(newRuleSnapshot(append(rules[:idx], rules[idx+1:]...)))
+	return true
+}
+
+// Move changes the priority of the named rule to the given position
+func (s *EmojiRuleSet) Move(name string, index int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := s.load()
+	idx := indexOf(rules, name)
+	if idx == -1 {
+		return false
+	}
+	rule := rules[idx]
+	rules = append(rules[:idx], rules[idx+1:]...)
+	s.snap.Store(newRuleSnapshot(insertRule(rules, index, rule)))
+	return true
+}
+
+// Rules returns a copy of the rules in priority order
+func (s *EmojiRuleSet) Rules() []EmojiRule {
+	return s.load()
+}
+
+// Match returns the emoji of the first rule matching the message and attributes, or "" if none does.
+// Rules without an emoji use the unicode theme glyph of their category.
+func (s *EmojiRuleSet) Match(msg string, attrs []slog.Attr) string {
+	if s == nil {
+		return ""
+	}
+	rule, ok := s.snap.Load().find(msg, attrs)
+	if !ok {
+		return ""
+	}
+	if rule.Emoji == "" {
+		return ThemeUnicode.Glyph(rule.Category)
+	}
+	return rule.Emoji
+}
+
+// load returns a private copy of the current rules
+func (s *EmojiRuleSet) load() []EmojiRule {
+	snap := s.snap.Load()
+	if snap == nil {
+		return nil
+	}
+	rules := make([]EmojiRule, len(snap.rules))
+	copy(rules, snap.rules)
+	return rules
+}
+
+// matchRecord matches a record against the rule set. The handler attributes
+// must already be qualified with their groups, record attributes are nested
+// under the currently open groups.
+func (s *EmojiRuleSet) matchRecord(handlerAttrs []slog.Attr, groups []string, r slog.Record) (EmojiRule, bool) {
+	if s == nil {
+		return EmojiRule{}, false
+	}
+	snap := s.snap.Load()
+	if snap == nil || !snap.useAttrs {
+		return snap.find(r.Message, nil)
+	}
+
+	attrs := make([]slog.Attr, 0, len(handlerAttrs)+r.NumAttrs())
+	attrs = append(attrs, handlerAttrs...)
+	var recAttrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		recAttrs = append(recAttrs, a)
+		return true
+	})
+	attrs = append(attrs, qualifyAttrs(groups, recAttrs)...)
+	return snap.find(r.Message, attrs)
+}
+
+func indexOf(rules []EmojiRule, name string) int {
+	for i := range rules {
+		if rules[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertRule(rules []EmojiRule, index int, rule EmojiRule) []EmojiRule {
+	if index < 0 || index > len(rules) {
+		index = len(rules)
+	}
+	rules = append(rules, EmojiRule{})
+	copy(rules[index+1:], rules[index:])
+	rules[index] = rule
+	return rules
+}
+
+// qualifyAttrs nests attrs inside the given groups
+func qualifyAttrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	for i := len(groups) - 1; i >= 0 && len(attrs) > 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
+// defaultEmojiRules is the built-in keyword list, in priority order
+var defaultEmojiRules = []EmojiRule{
+	// Health status patterns - highest priority
+	{Name: "health-excellent", Emoji: "💚", Category: CategoryHealthExcellent, Requires: []string{"health"}, Keywords: []string{"excellent"}},
+	{Name: "health-good", Emoji: "🟡", Category: CategoryHealthGood, Requires: []string{"health"}, Keywords: []string{"good"}},
+	{Name: "health-degraded", Emoji: "🟠", Category: CategoryHealthDegraded, Requires: []string{"health"}, Keywords: []string{"degraded"}},
+	{Name: "health-critical", Emoji: "🔴", Category: CategoryHealthCritical, Requires: []string{"health"}, Keywords: []string{"critical"}},
+
+	// System states
+	{Name: "shutdown", Emoji: "🛑", Category: CategoryShutdown, Keywords: []string{"shutdown", "stopping"}},
+	{Name: "start", Emoji: "🚀", Category: CategoryStart, Keywords: []string{"start", "parser is running"}},
+	{Name: "metrics", Emoji: "📊", Category: CategoryMetrics, Keywords: []string{"metrics"}},
+	{Name: "success", Emoji: "🎉", Category: CategorySuccess, Keywords: []string{"success"}},
+	{Name: "cleanup", Emoji: "🧹", Category: CategoryCleanup, Keywords: []string{"cleanup"}},
+
+	// Operations
+	{Name: "config", Emoji: "⚙️", Category: CategoryConfig, Keywords: []string{"config", "setting"}},
+	{Name: "connect", Emoji: "🔌", Category: CategoryConnect, Keywords: []string{"connect", "websocket"}},
+	{Name: "failed", Emoji: "❌", Category: CategoryFailure, Keywords: []string{"failed"}},
+	{Name: "game", Emoji: "🎰", Category: CategoryGame, Keywords: []string{"table", "game", "casino"}},
+	{Name: "statistics", Emoji: "📊", Category: CategoryMetrics, Keywords: []string{"statistics"}},
+	{Name: "loading", Emoji: "⏳", Category: CategoryLoading, Keywords: []string{"loading", "processing"}},
+	{Name: "creating", Emoji: "🆕", Category: CategoryCreate, Keywords: []string{"creating"}},
+}
@@ -0,0 +1,119 @@
+package mojilog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultEmojiRules(t *testing.T) {
+	rules := DefaultEmojiRules()
+
+	testCases := []struct {
+		msg      string
+		expected string
+	}{
+		{"Health check: EXCELLENT", "💚"},
+		{"health is degraded", "🟠"},
+		{"Stopping workers", "🛑"},
+		{"Starting application", "🚀"},
+		{"Loading config", "⚙️"},
+		{"Connection failed", "🔌"},
+		{"Request failed", "❌"},
+		{"Processing data", "⏳"},
+		{"nothing to see here", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := rules.Match(tc.msg, nil); got != tc.expected {
+			t.Errorf("Match(%q): expected %q, got %q", tc.msg, tc.expected, got)
+		}
+	}
+}
+
+func TestEmojiRuleSetEditing(t *testing.T) {
+	rules, err := NewEmojiRuleSet(
+		EmojiRule{Name: "cache", Emoji: "🗄️", Keywords: []string{"cache"}},
+		EmojiRule{Name: "miss", Emoji: "🕳️", Keywords: []string{"Miss"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rules.Match("cache miss", nil); got != "🗄️" {
+		t.Errorf("expected first rule to win, got %q", got)
+	}
+
+	if !rules.Move("miss", 0) {
+		t.Fatal("Move: rule not found")
+	}
+	if got := rules.Match("cache miss", nil); got != "🕳️" {
+		t.Errorf("expected moved rule to win, got %q", got)
+	}
+
+	if err := rules.Replace("miss", EmojiRule{Name: "hit", Emoji: "🎯", Keywords: []string{"hit"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rules.Match("cache miss", nil); got != "🗄️" {
+		t.Errorf("expected replaced rule to be gone, got %q", got)
+	}
+
+	if err := rules.Add(EmojiRule{Name: "cache", Emoji: "x", Keywords: []string{"x"}}); err == nil {
+		t.Error("expected duplicate rule name to be rejected")
+	}
+	if err := rules.Add(EmojiRule{Name: "empty", Emoji: "x"}); err == nil {
+		t.Error("expected rule without keywords to be rejected")
+	}
+
+	if !rules.Remove("cache") {
+		t.Fatal("Remove: rule not found")
+	}
+	if got := rules.Match("cache miss", nil); got != "" {
+		t.Errorf("expected no match, got %q", got)
+	}
+	if n := len(rules.Rules()); n != 1 {
+		t.Errorf("expected 1 rule left, got %d", n)
+	}
+}
+
+func TestEmojiRuleAttrConditions(t *testing.T) {
+	rules, err := NewEmojiRuleSet(
+		EmojiRule{Name: "server-error", Emoji: "🔥", Attrs: []string{"http.status>=500"}},
+		EmojiRule{Name: "slow", Emoji: "🐢", Attrs: []string{"duration>1s"}},
+		EmojiRule{Name: "db-error", Emoji: "🗄️", Keywords: []string{"query"}, Attrs: []string{"component=db", "error"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	handler := NewEmojiHandler(slog.NewTextHandler(&buf, nil))
+	handler.SetEmojiRules(rules)
+	logger := slog.New(handler)
+
+	logger.Info("request done", slog.Group("http", slog.Int("status", 503)))
+	logger.WithGroup("http").Info("request done", "status", 200)
+	logger.Info("request done", "duration", 2*time.Second)
+	logger.With("component", "db").Info("query done", "error", "timeout")
+	logger.With("component", "db").Info("query done")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{"🔥", "ℹ️", "🐢", "🗄️", "ℹ️"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), buf.String())
+	}
+	for i, emoji := range expected {
+		if !strings.Contains(lines[i], `msg="`+emoji) {
+			t.Errorf("line %d: expected emoji %s, got %s", i, emoji, lines[i])
+		}
+	}
+}
+
+func TestEmojiRuleInvalidCondition(t *testing.T) {
+	_, err := NewEmojiRuleSet(EmojiRule{Name: "bad", Emoji: "x", Attrs: []string{">=500"}})
+	if err == nil {
+		t.Error("expected condition without key to be rejected")
+	}
+}
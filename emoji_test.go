@@ -3,6 +3,7 @@ package mojilog
 import (
 	"bytes"
 	"log/slog"
+	"strings"
 	"testing"
 )
 
@@ -80,3 +81,47 @@ func containsEmoji(s, emoji string) bool {
 	}
 	return false
 }
+
+func TestEmojiPlacement(t *testing.T) {
+	testCases := []struct {
+		placement EmojiPlacement
+		key       string
+		expected  string
+	}{
+		{EmojiPrefix, "", `"msg":"🚀 Starting application"`},
+		{EmojiSuffix, "", `"msg":"Starting application 🚀"`},
+		{EmojiAttr, "", `"msg":"Starting application","emoji":"🚀"`},
+		{EmojiAttr, "icon", `"msg":"Starting application","icon":"🚀"`},
+		{EmojiOff, "", `"msg":"Starting application"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.placement.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := NewEmojiHandler(slog.NewJSONHandler(&buf, nil))
+			handler.SetPlacement(tc.placement)
+			if tc.key != "" {
+				handler.SetEmojiKey(tc.key)
+			}
+			slog.New(handler).Info("Starting application")
+
+			if !strings.Contains(buf.String(), tc.expected) {
+				t.Errorf("expected %s in output, got %s", tc.expected, buf.String())
+			}
+		})
+	}
+
+	// The attribute stays out of groups so alerting can match on it
+	var buf bytes.Buffer
+	handler := NewEmojiHandler(slog.NewJSONHandler(&buf, nil))
+	handler.SetPlacement(EmojiAttr)
+	slog.New(handler).With("service", "api").WithGroup("http").Info("Starting application", "port", 8080)
+	expected := `"msg":"Starting application","service":"api","emoji":"🚀","http":{"port":8080}}`
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected %s in output, got %s", expected, buf.String())
+	}
+
+	if _, err := ParseEmojiPlacement("sideways"); err == nil {
+		t.Error("expected unknown placement to be rejected")
+	}
+}
@@ -0,0 +1,190 @@
+package mojilog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables read by EnvOptions and by Get when the global logger
+// has not been initialized
+const (
+	LevelEnv    = "MOJILOG_LEVEL"    // level name, e.g. debug
+	FormatEnv   = "MOJILOG_FORMAT"   // pretty, pretty-json, json or logfmt
+	SourceEnv   = "MOJILOG_SOURCE"   // true or false
+	ColorEnv    = "MOJILOG_COLOR"    // auto, always or never
+	EmojiEnv    = "MOJILOG_EMOJI"    // prefix, attr, suffix, off, or true/false
+	OutputEnv   = "MOJILOG_OUTPUT"   // stdout, stderr or a file path to append to
+	TimezoneEnv = "MOJILOG_TIMEZONE" // IANA name such as Asia/Seoul, UTC or Local
+)
+
+// envConfig is the configuration found in the environment
+type envConfig struct {
+	level   *slog.Level
+	vmodule string
+	opts    []Option
+}
+
+// EnvOptions returns options for the MOJILOG_* variables that are set.
+// Every invalid value is reported in the error, options are returned for the valid ones.
+func EnvOptions() ([]Option, error) {
+	cfg, err := readEnv()
+	opts := cfg.opts
+	if cfg.level != nil {
+		opts = append([]Option{WithLevel(*cfg.level)}, opts...)
+	}
+	return opts, err
+}
+
+// readEnv parses the MOJILOG_* variables
+func readEnv() (envConfig, error) {
+	var cfg envConfig
+	var errs []error
+	lookup := func(name string, parse func(string) error) {
+		value, ok := os.LookupEnv(name)
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return
+		}
+		if err := parse(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q: %w", name, value, err))
+		}
+	}
+
+	lookup(LevelEnv, func(s string) error {
+		level, ok := LevelByName(s)
+		if !ok {
+			return fmt.Errorf("unknown level")
+		}
+		cfg.level = &level
+		return nil
+	})
+	lookup(FormatEnv, func(s string) error {
+		f, err := ParseFormat(s)
+		if err != nil {
+			return err
+		}
+		cfg.opts = append(cfg.opts, WithFormat(f))
+		return nil
+	})
+	lookup(SourceEnv, func(s string) error {
+		addSource, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("want true or false")
+		}
+		cfg.opts = append(cfg.opts, WithSource(addSource))
+		return nil
+	})
+	lookup(ColorEnv, func(s string) error {
+		mode, err := ParseColorMode(s)
+		if err != nil {
+			return err
+		}
+		cfg.opts = append(cfg.opts, WithColor(mode))
+		return nil
+	})
+	lookup(EmojiEnv, func(s string) error {
+		placement, err := ParseEmojiPlacement(s)
+		if err != nil {
+			on, boolErr := strconv.ParseBool(s)
+			if boolErr != nil {
+				return err
+			}
+			placement = EmojiPrefix
+			if !on {
+				placement = EmojiOff
+			}
+		}
+		cfg.opts = append(cfg.opts, WithEmojiPlacement(placement))
+		return nil
+	})
+	lookup(OutputEnv, func(s string) error {
+		switch s {
+		case "stdout":
+			cfg.opts = append(cfg.opts, WithWriter(os.Stdout))
+		case "stderr":
+			cfg.opts = append(cfg.opts, WithWriter(os.Stderr))
+		default:
+			if err := checkOutputPath(s); err != nil {
+				return err
+			}
+			cfg.opts = append(cfg.opts, WithWriter(outputFileFor(s)))
+		}
+		return nil
+	})
+	lookup(TimezoneEnv, func(s string) error {
+		loc, err := time.LoadLocation(s)
+		if err != nil {
+			return err
+		}
+		cfg.opts = append(cfg.opts, WithLocation(loc))
+		return nil
+	})
+	lookup(VModuleEnv, func(s string) error {
+		if _, err := ParseVModule(s); err != nil {
+			return err
+		}
+		cfg.vmodule = s
+		return nil
+	})
+
+	return cfg, errors.Join(errs...)
+}
+
+// checkOutputPath reports whether path can be a log file, without creating it
+func checkOutputPath(path string) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return errors.New("is a directory")
+		}
+		return nil
+	}
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+// outputFiles holds an outputFile per absolute path, so reading the
+// environment again doesn't open the same file again
+var outputFiles sync.Map
+
+// outputFile appends to a file it opens on the first write, so only loggers
+// that are built and used hold it open
+type outputFile struct {
+	path string
+	once sync.Once
+	f    *os.File
+	err  error
+}
+
+// outputFileFor returns the shared outputFile for path
+func outputFileFor(path string) *outputFile {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	o, _ := outputFiles.LoadOrStore(path, &outputFile{path: path})
+	return o.(*outputFile)
+}
+
+// Write implements io.Writer
+func (o *outputFile) Write(p []byte) (int, error) {
+	o.once.Do(func() {
+		o.f, o.err = os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	})
+	if o.err != nil {
+		return 0, o.err
+	}
+	return o.f.Write(p)
+}
@@ -0,0 +1,120 @@
+package mojilog
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvOptions(t *testing.T) {
+	t.Setenv(LevelEnv, "debug")
+	t.Setenv(FormatEnv, "json")
+	t.Setenv(SourceEnv, "true")
+	t.Setenv(EmojiEnv, "attr")
+	t.Setenv(TimezoneEnv, "UTC")
+
+	opts, err := EnvOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	New(append(opts, WithWriter(&buf))...).Debug("Starting application")
+	out := buf.String()
+	for _, want := range []string{`"level":"DEBUG"`, `"source":`, `"emoji":"🚀"`, `Z","level"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+}
+
+func TestEnvOptionsInvalid(t *testing.T) {
+	t.Setenv(LevelEnv, "loud")
+	t.Setenv(FormatEnv, "xml")
+	t.Setenv(SourceEnv, "maybe")
+	t.Setenv(ColorEnv, "always")
+	t.Setenv(TimezoneEnv, "Mars/Olympus")
+
+	opts, err := EnvOptions()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, name := range []string{LevelEnv, FormatEnv, SourceEnv, TimezoneEnv} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected %s to be reported, got %v", name, err)
+		}
+	}
+	if len(opts) != 1 {
+		t.Errorf("expected an option for the valid variable only, got %d", len(opts))
+	}
+}
+
+func TestGetFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv(OutputEnv, path)
+	t.Setenv(FormatEnv, "logfmt")
+	t.Setenv(LevelEnv, "warn")
+
+	Reset()
+	defer Reset()
+
+	Info("hidden")
+	Warn("disk almost full")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "level=WARN") || !strings.Contains(out, "disk almost full") {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestEnvOptionsOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv(OutputEnv, path)
+
+	// Reading the environment doesn't open the file, building and using a logger does.
+	// Every read shares one writer.
+	var loggers []*slog.Logger
+	var writers []io.Writer
+	for i := 0; i < 3; i++ {
+		opts, err := EnvOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected EnvOptions not to create the file, got %v", err)
+		}
+		var o Options
+		for _, opt := range opts {
+			opt(&o)
+		}
+		writers = append(writers, o.Writer)
+		loggers = append(loggers, New(append(opts, WithFormat(FormatJSON))...))
+	}
+	if writers[0] != writers[1] || writers[1] != writers[2] {
+		t.Error("expected one shared writer per output path")
+	}
+	for _, logger := range loggers {
+		logger.Info("written")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "written"); n != 3 {
+		t.Errorf("expected 3 lines, got %q", data)
+	}
+
+	// Invalid paths are reported up front
+	t.Setenv(OutputEnv, filepath.Join(t.TempDir(), "missing", "app.log"))
+	if _, err := EnvOptions(); err == nil || !strings.Contains(err.Error(), OutputEnv) {
+		t.Errorf("expected %s to be reported, got %v", OutputEnv, err)
+	}
+}
@@ -0,0 +1,112 @@
+package mojilog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxErrorDepth caps how deep error chains are followed, so an Unwrap that
+// returns its own receiver or a cycle can't recurse forever
+const maxErrorDepth = 16
+
+// errorChain formats err with the errors it wraps. A wrapped message that
+// already ends err's message is not repeated, errors.Join members are listed
+// in brackets, e.g. "load: [open a.yaml: not found; open b.yaml: not found]".
+// Errors with a nil receiver print "<nil>" instead of panicking.
+func errorChain(err error) string {
+	return errorChainDepth(err, maxErrorDepth)
+}
+
+func errorChainDepth(err error, depth int) string {
+	msg, ok := errorMessage(err)
+	inner, joined := unwrapError(err)
+	if len(inner) == 0 || depth == 0 {
+		return msg
+	}
+	if !joined {
+		innerMsg, _ := errorMessage(inner[0])
+		if prefix, ok := strings.CutSuffix(msg, innerMsg); ok {
+			return prefix + errorChainDepth(inner[0], depth-1)
+		}
+		return msg + ": " + errorChainDepth(inner[0], depth-1)
+	}
+
+	var msgs, chains []string
+	for _, e := range inner {
+		if e != nil {
+			m, _ := errorMessage(e)
+			msgs = append(msgs, m)
+			chains = append(chains, errorChainDepth(e, depth-1))
+		}
+	}
+	// errors.Join has no message of its own, and its Error method panics
+	// if a member's does
+	if !ok || msg == strings.Join(msgs, "\n") {
+		return "[" + strings.Join(chains, "; ") + "]"
+	}
+	return msg
+}
+
+// errorMessage returns err's message. If the Error method panics, e.g. on a
+// nil receiver, it returns fmt's "<nil>" or panic message and false.
+func errorMessage(err error) (msg string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			msg, ok = fmt.Sprint(err), false
+		}
+	}()
+	return err.Error(), true
+}
+
+// unwrapError returns the errors err wraps, joined is true for an
+// Unwrap() []error method. An Unwrap method that panics, e.g. on a nil
+// receiver, wraps nothing.
+func unwrapError(err error) (inner []error, joined bool) {
+	defer func() {
+		if recover() != nil {
+			inner, joined = nil, false
+		}
+	}()
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if e := u.Unwrap(); e != nil {
+			return []error{e}, false
+		}
+	case interface{ Unwrap() []error }:
+		return u.Unwrap(), true
+	}
+	return nil, false
+}
+
+// errorStack returns the "%+v" form of the first error in err's chain that
+// implements fmt.Formatter if it spans several lines, as errors carrying a
+// stack trace print it. It returns "" otherwise.
+func errorStack(err error) string {
+	f := findFormatter(err, maxErrorDepth)
+	if f == nil {
+		return ""
+	}
+	stack := strings.TrimRight(fmt.Sprintf("%+v", f), "\n")
+	if !strings.Contains(stack, "\n") {
+		return ""
+	}
+	return stack
+}
+
+// findFormatter returns the first error in err's chain implementing
+// fmt.Formatter, in the order of errors.As, or nil
+func findFormatter(err error, depth int) fmt.Formatter {
+	if f, ok := err.(fmt.Formatter); ok {
+		return f
+	}
+	if depth == 0 {
+		return nil
+	}
+	inner, _ := unwrapError(err)
+	for _, e := range inner {
+		if f := findFormatter(e, depth-1); f != nil {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package mojilog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// opaqueError wraps an error without including its message
+type opaqueError struct{ err error }
+
+func (e opaqueError) Error() string { return "request failed" }
+func (e opaqueError) Unwrap() error { return e.err }
+
+// stackError prints a fake stack trace with %+v like errors from github.com/pkg/errors
+type stackError struct{ msg string }
+
+func (e stackError) Error() string { return e.msg }
+
+func (e stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\nmain.load\n\t/src/main.go:12", e.msg)
+		return
+	}
+	io.WriteString(s, e.msg)
+}
+
+// codeError has pointer methods that panic on a nil receiver
+type codeError struct {
+	code int
+	err  error
+}
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+func (e *codeError) Unwrap() error { return e.err }
+
+// loopError unwraps to itself
+type loopError struct{}
+
+func (e loopError) Error() string { return "loop" }
+func (e loopError) Unwrap() error { return e }
+
+func TestErrorChain(t *testing.T) {
+	notFound := errors.New("not found")
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{notFound, "not found"},
+		{fmt.Errorf("load: %w", notFound), "load: not found"},
+		{opaqueError{notFound}, "request failed: not found"},
+		{fmt.Errorf("load: %w", errors.Join(errors.New("a.yaml"), opaqueError{notFound})), "load: [a.yaml; request failed: not found]"},
+		{fmt.Errorf("%w and %w", notFound, io.EOF), "not found and EOF"},
+	}
+
+	for _, tc := range testCases {
+		if got := errorChain(tc.err); got != tc.want {
+			t.Errorf("errorChain(%q) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	if got := errorStack(fmt.Errorf("load: %w", stackError{"boom"})); got != "boom\nmain.load\n\t/src/main.go:12" {
+		t.Errorf("unexpected stack %q", got)
+	}
+	if got := errorStack(errors.New("boom")); got != "" {
+		t.Errorf("expected no stack, got %q", got)
+	}
+}
+
+func TestErrorChainNilReceiver(t *testing.T) {
+	var nilErr *codeError
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{nilErr, "<nil>"},
+		{fmt.Errorf("load: %w", nilErr), "load: <nil>"},
+		{&codeError{code: 3, err: nilErr}, "code 3: <nil>"},
+		{errors.Join(nilErr, io.EOF), "[<nil>; EOF]"},
+		{loopError{}, "loop"},
+		{opaqueError{loopError{}}, "request failed: loop"},
+	}
+
+	for _, tc := range testCases {
+		if got := errorChain(tc.err); got != tc.want {
+			t.Errorf("errorChain(%#v) = %q, want %q", tc.err, got, tc.want)
+		}
+		if got := errorStack(tc.err); got != "" {
+			t.Errorf("errorStack(%#v) = %q, want no stack", tc.err, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	slog.New(NewPrettyHandler(&buf, nil)).Error("failed", "err", error(nilErr))
+	slog.New(NewPrettyJSONHandler(&buf, nil)).Error("failed", "err", fmt.Errorf("load: %w", nilErr))
+	if !strings.Contains(buf.String(), "err=<nil>") || !strings.Contains(buf.String(), `"err": "load: \u003cnil\u003e"`) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,55 @@
+package mojilog
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	exitMu    sync.Mutex
+	exitHooks []func()
+	exitFunc  = os.Exit
+)
+
+// RegisterExitHook adds a function that runs before Fatal exits or Panic panics,
+// e.g. to flush buffered sinks or close files. Hooks run in registration order.
+func RegisterExitHook(hook func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// SetExitFunc replaces os.Exit as the function Fatal calls after the exit hooks,
+// nil restores os.Exit. Tests use it to observe Fatal without ending the process.
+func SetExitFunc(fn func(code int)) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitFunc = fn
+}
+
+// runExitHooks runs the registered hooks, a panicking hook doesn't stop the others
+func runExitHooks() {
+	exitMu.Lock()
+	hooks := append([]func(){}, exitHooks...)
+	exitMu.Unlock()
+
+	for _, hook := range hooks {
+		func() {
+			defer func() { _ = recover() }()
+			hook()
+		}()
+	}
+}
+
+// exit runs the exit hooks and ends the process through the exit function
+func exit(code int) {
+	runExitHooks()
+
+	exitMu.Lock()
+	fn := exitFunc
+	exitMu.Unlock()
+	fn(code)
+}
@@ -0,0 +1,72 @@
+package mojilog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// captureGlobal points the global logger at a buffer for the duration of a test
+func captureGlobal(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := globalLogger.Load()
+	globalLogger.Store(SetupLogger(&buf, slog.LevelInfo, "json", true))
+	t.Cleanup(func() { globalLogger.Store(prev) })
+	return &buf
+}
+
+func TestFatal(t *testing.T) {
+	buf := captureGlobal(t)
+
+	var calls []string
+	exitHooks = nil
+	t.Cleanup(func() { exitHooks = nil })
+	RegisterExitHook(func() { calls = append(calls, "flush") })
+	RegisterExitHook(func() { panic("broken hook") })
+	RegisterExitHook(func() { calls = append(calls, "close") })
+
+	code := -1
+	SetExitFunc(func(c int) { code = c; calls = append(calls, "exit") })
+	defer SetExitFunc(nil)
+
+	Fatal("cannot continue", "reason", "disk full")
+
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if got := strings.Join(calls, ","); got != "flush,close,exit" {
+		t.Errorf("expected hooks before exit, got %s", got)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"level":"FATAL"`) {
+		t.Errorf("expected FATAL level, got %s", out)
+	}
+	if !strings.Contains(out, "exit_test.go") {
+		t.Errorf("expected caller to be the test file, got %s", out)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	buf := captureGlobal(t)
+
+	hookRan := false
+	exitHooks = nil
+	t.Cleanup(func() { exitHooks = nil })
+	RegisterExitHook(func() { hookRan = true })
+
+	defer func() {
+		if r := recover(); r != "invariant violated" {
+			t.Errorf("expected panic with message, got %v", r)
+		}
+		if !hookRan {
+			t.Error("expected exit hook to run before panicking")
+		}
+		if !strings.Contains(buf.String(), `"level":"PANIC"`) {
+			t.Errorf("expected PANIC level, got %s", buf.String())
+		}
+	}()
+
+	Panic("invariant violated", "id", 42)
+}
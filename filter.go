@@ -0,0 +1,121 @@
+package mojilog
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// FilterMode says whether an AttrFilter hides the listed attributes or shows only them
+type FilterMode int
+
+const (
+	// FilterDeny leaves out the listed attributes (default)
+	FilterDeny FilterMode = iota
+	// FilterAllow shows only the listed attributes
+	FilterAllow
+)
+
+// String returns the mode name
+func (m FilterMode) String() string {
+	switch m {
+	case FilterDeny:
+		return "deny"
+	case FilterAllow:
+		return "allow"
+	default:
+		return fmt.Sprintf("FilterMode(%d)", int(m))
+	}
+}
+
+// AttrFilter selects the attributes a handler shows. Keys are dotted paths
+// of an attribute's groups and key, e.g. "http.request.method", and a group's
+// path covers all of its members. The time, level, message and source are
+// never filtered. The zero value shows every attribute.
+type AttrFilter struct {
+	Mode FilterMode
+	Keys []string
+}
+
+// SkipKeys returns a filter that leaves out the attributes at the given paths
+func SkipKeys(keys ...string) AttrFilter {
+	return AttrFilter{Mode: FilterDeny, Keys: append([]string{}, keys...)}
+}
+
+// OnlyKeys returns a filter that shows only the attributes at the given paths
+func OnlyKeys(keys ...string) AttrFilter {
+	return AttrFilter{Mode: FilterAllow, Keys: append([]string{}, keys...)}
+}
+
+// VerboseKeys is a preset of attributes that are mostly noise while developing,
+// e.g. WithSkipKeys(mojilog.VerboseKeys...)
+var VerboseKeys = []string{
+	"service",
+	"version",
+	"metric_name",
+	"metric_value",
+	"environment",
+	"pid",
+}
+
+// keeps reports whether the attribute at path is shown
+func (f AttrFilter) keeps(path string) bool {
+	listed := false
+	for _, key := range f.Keys {
+		if path == key || strings.HasPrefix(path, key) && path[len(key)] == '.' {
+			listed = true
+			break
+		}
+	}
+	return listed == (f.Mode == FilterAllow)
+}
+
+// filter removes the attributes f doesn't keep from a, whose key is qualified
+// with groups. Groups left empty are removed, slog.Attr{} is returned if
+// nothing is kept.
+func (f AttrFilter) filter(groups []string, a slog.Attr) slog.Attr {
+	if f.Mode == FilterDeny && len(f.Keys) == 0 {
+		return a
+	}
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if !f.keeps(attrPath(groups, a.Key)) {
+			return slog.Attr{}
+		}
+		return a
+	}
+
+	if a.Key != "" {
+		groups = append(groups[:len(groups):len(groups)], a.Key)
+	}
+	var kept []slog.Attr
+	for _, m := range a.Value.Group() {
+		if m = f.filter(groups, m); !m.Equal(slog.Attr{}) {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == 0 {
+		return slog.Attr{}
+	}
+	a.Value = slog.GroupValue(kept...)
+	return a
+}
+
+// filterAttrs applies filter to attrs and drops the empty ones
+func (f AttrFilter) filterAttrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	kept := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a = f.filter(groups, a); !a.Equal(slog.Attr{}) {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+// attrPath joins groups and key with dots
+func attrPath(groups []string, key string) string {
+	if len(groups) == 0 {
+		return key
+	}
+	return strings.Join(groups, ".") + "." + key
+}
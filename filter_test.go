@@ -0,0 +1,58 @@
+package mojilog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAttrFilterKeeps(t *testing.T) {
+	testCases := []struct {
+		filter AttrFilter
+		path   string
+		want   bool
+	}{
+		{AttrFilter{}, "pid", true},
+		{SkipKeys(VerboseKeys...), "pid", false},
+		{SkipKeys(VerboseKeys...), "app.pid", true},
+		{SkipKeys("http.request"), "http.request.method", false},
+		{SkipKeys("http.request"), "http.requests", true},
+		{OnlyKeys("http.request", "user"), "http.request.method", true},
+		{OnlyKeys("http.request", "user"), "http.status", false},
+		{OnlyKeys("http.request", "user"), "user", true},
+		{OnlyKeys(), "user", false},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.filter.keeps(tc.path); got != tc.want {
+			t.Errorf("%v %v keeps %q = %v, want %v", tc.filter.Mode, tc.filter.Keys, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestAttrFilterFormats(t *testing.T) {
+	log := func(logger *slog.Logger) {
+		logger.With("version", "1.2.0").WithGroup("http").
+			Info("served", "status", 200, slog.Group("request", "method", "GET", "token", "abc"))
+	}
+
+	for _, format := range []Format{FormatPretty, FormatPrettyJSON, FormatJSON, FormatLogfmt} {
+		var buf bytes.Buffer
+		log(New(WithWriter(&buf), WithFormat(format), WithColor(ColorNever), WithSkipKeys("http.request.token", "version")))
+		out := buf.String()
+		if strings.Contains(out, "abc") || strings.Contains(out, "1.2.0") || !strings.Contains(out, "GET") || !strings.Contains(out, "200") {
+			t.Errorf("%s: expected the token and version to be skipped, got %s", format, out)
+		}
+
+		buf.Reset()
+		log(New(WithWriter(&buf), WithFormat(format), WithColor(ColorNever), WithOnlyKeys("http.request.method")))
+		out = buf.String()
+		if strings.Contains(out, "abc") || strings.Contains(out, "1.2.0") || strings.Contains(out, "200") || !strings.Contains(out, "GET") {
+			t.Errorf("%s: expected only the method, got %s", format, out)
+		}
+		if !strings.Contains(out, "served") {
+			t.Errorf("%s: expected the message to be kept, got %s", format, out)
+		}
+	}
+}
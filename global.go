@@ -2,47 +2,166 @@ package mojilog
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	globalLogger *slog.Logger
-	once         sync.Once
+	globalMu     sync.Mutex // serializes writers
+	globalLogger atomic.Pointer[slog.Logger]
+
+	// globalLevel is the level of the global logger. Loggers from the Setup
+	// functions keep the level they were created with until SetLevel is called,
+	// then they follow globalLevel too. That way SetLevel reaches every logger
+	// while creating one doesn't change the level of the others.
+	globalLevel = new(slog.LevelVar)
+	// levelChanges counts SetLevel calls, see setupLevel
+	levelChanges atomic.Uint64
+
+	// initialDefault is slog's default logger, restored by Reset
+	initialDefault = slog.Default()
 )
 
 // InitGlobal initializes the global logger with emoji support
-// This should be called once at application startup
+// This should be called once at application startup, later calls are ignored
+// until Reset. Use Configure to replace the global logger. Format is "text"
+// (default), "json" or "pretty-json". See New for more options.
 func InitGlobal(level slog.Level, format string, addSource bool) {
-	once.Do(func() {
-		// Choose logger format
-		switch format {
-		case "json":
-			// Regular JSON for machine processing
-			globalLogger = SetupLogger(os.Stdout, level, format, addSource)
-		case "pretty-json":
-			// Pretty formatted JSON with colors
-			globalLogger = SetupPrettyJSONLogger(os.Stdout, level, addSource)
-		default:
-			// Pretty text format (default)
-			globalLogger = SetupPrettyLogger(os.Stdout, level, addSource)
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if globalLogger.Load() != nil {
+		return
+	}
+
+	// Unknown formats fall back to pretty text
+	f, _ := ParseFormat(format)
+	globalLevel.Set(level)
+
+	// Module levels from the environment, SetModuleLevel and SetVModule also work
+	if spec := os.Getenv(VModuleEnv); spec != "" {
+		if err := SetVModule(spec); err != nil {
+			fmt.Fprintf(os.Stderr, "mojilog: ignoring %s: %v\n", VModuleEnv, err)
 		}
+	}
+	setGlobal(New(WithFormat(f), WithSource(addSource)))
+}
+
+// Configure replaces the global logger and slog's default logger with one built
+// from opts. Loggers already derived with With keep writing to the old one.
+// Without WithLevel the logger follows SetLevel.
+func Configure(opts ...Option) {
+	logger := New(opts...)
 
-		// Also set as default slog logger
-		slog.SetDefault(globalLogger)
-	})
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	setGlobal(logger)
+}
+
+// Reset restores the uninitialized state: the next Get configures the global
+// logger from the environment again, the level goes back to info, module
+// levels and pending reverts of the level handler are cleared and slog's
+// default logger is restored. Meant for tests.
+func Reset() {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	globalLogger.Store(nil)
+	slog.SetDefault(initialDefault)
+	globalLevel.Set(slog.LevelInfo)
+	cancelReverts()
+	_ = SetVModule("")
 }
 
 // Get returns the global logger instance
-// If not initialized, it creates a default one
+// If not initialized, it creates one configured by the MOJILOG_* environment variables, see EnvOptions
 func Get() *slog.Logger {
-	if globalLogger == nil {
-		InitGlobal(slog.LevelInfo, "text", false)
+	if logger := globalLogger.Load(); logger != nil {
+		return logger
+	}
+
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if globalLogger.Load() == nil {
+		initFromEnv()
 	}
-	return globalLogger
+	return globalLogger.Load()
+}
+
+// initFromEnv initializes the global logger from the environment. Invalid
+// values are reported on stderr and left at their defaults. The caller must
+// hold globalMu.
+func initFromEnv() {
+	cfg, err := readEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mojilog: invalid environment, using defaults for:\n%v\n", err)
+	}
+	if cfg.level != nil {
+		globalLevel.Set(*cfg.level)
+	}
+	if cfg.vmodule != "" {
+		_ = SetVModule(cfg.vmodule)
+	}
+	setGlobal(New(cfg.opts...))
+}
+
+// setGlobal sets the global logger and the default slog logger, the caller must hold globalMu
+func setGlobal(logger *slog.Logger) {
+	globalLogger.Store(logger)
+	slog.SetDefault(logger)
+}
+
+// SetLevel changes the minimum level of the global logger and of every logger
+// built by the Setup functions, including loggers derived with With and WithGroup.
+// It takes effect immediately.
+func SetLevel(level slog.Level) {
+	globalLevel.Set(level)
+	levelChanges.Add(1)
+}
+
+// setupLevel is the level of a logger from a Setup function
+type setupLevel struct {
+	level   slog.Level
+	changes uint64 // levelChanges when the logger was created
+}
+
+// newSetupLevel returns a level that is level until SetLevel is called
+func newSetupLevel(level slog.Level) *setupLevel {
+	return &setupLevel{level: level, changes: levelChanges.Load()}
+}
+
+// Level implements slog.Leveler
+func (l *setupLevel) Level() slog.Level {
+	if levelChanges.Load() != l.changes {
+		return globalLevel.Level()
+	}
+	return l.level
+}
+
+// GetLevel returns the current global minimum level
+func GetLevel() slog.Level {
+	return globalLevel.Level()
+}
+
+// Leveler returns the global level, e.g. to use as HandlerOptions.Level of
+// custom handlers that should follow SetLevel. It is read-only, change the
+// level with SetLevel so the Setup loggers follow too.
+func Leveler() slog.Leveler {
+	return globalLeveler{}
+}
+
+// globalLeveler reads globalLevel without exposing it
+type globalLeveler struct{}
+
+// Level implements slog.Leveler
+func (globalLeveler) Level() slog.Level {
+	return globalLevel.Level()
 }
 
 // With returns a new logger with the given attributes
@@ -55,57 +174,310 @@ func WithGroup(name string) *slog.Logger {
 	return Get().WithGroup(name)
 }
 
+// Trace logs at trace level
+func Trace(msg string, args ...any) {
+	logWithCaller(context.Background(), LevelTrace, msg, args...)
+}
+
 // Debug logs at debug level
 func Debug(msg string, args ...any) {
-	logWithCaller(slog.LevelDebug, msg, args...)
+	logWithCaller(context.Background(), slog.LevelDebug, msg, args...)
 }
 
 // Info logs at info level
 func Info(msg string, args ...any) {
-	logWithCaller(slog.LevelInfo, msg, args...)
+	logWithCaller(context.Background(), slog.LevelInfo, msg, args...)
+}
+
+// Notice logs at notice level
+func Notice(msg string, args ...any) {
+	logWithCaller(context.Background(), LevelNotice, msg, args...)
 }
 
 // Warn logs at warn level
 func Warn(msg string, args ...any) {
-	logWithCaller(slog.LevelWarn, msg, args...)
+	logWithCaller(context.Background(), slog.LevelWarn, msg, args...)
 }
 
 // Error logs at error level
 func Error(msg string, args ...any) {
-	logWithCaller(slog.LevelError, msg, args...)
+	logWithCaller(context.Background(), slog.LevelError, msg, args...)
+}
+
+// Critical logs at critical level
+func Critical(msg string, args ...any) {
+	logWithCaller(context.Background(), LevelCritical, msg, args...)
+}
+
+// Fatal logs at fatal level, runs the exit hooks and exits with status 1
+func Fatal(msg string, args ...any) {
+	logWithCaller(context.Background(), LevelFatal, msg, args...)
+	exit(1)
+}
+
+// Panic logs at panic level, runs the exit hooks and panics with the message
+func Panic(msg string, args ...any) {
+	logWithCaller(context.Background(), LevelPanic, msg, args...)
+	runExitHooks()
+	panic(msg)
+}
+
+// TraceContext logs at trace level with ctx
+func TraceContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, LevelTrace, msg, args...)
+}
+
+// DebugContext logs at debug level with ctx
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, slog.LevelDebug, msg, args...)
+}
+
+// InfoContext logs at info level with ctx
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, slog.LevelInfo, msg, args...)
+}
+
+// NoticeContext logs at notice level with ctx
+func NoticeContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, LevelNotice, msg, args...)
+}
+
+// WarnContext logs at warn level with ctx
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, slog.LevelWarn, msg, args...)
+}
+
+// ErrorContext logs at error level with ctx
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, slog.LevelError, msg, args...)
+}
+
+// CriticalContext logs at critical level with ctx
+func CriticalContext(ctx context.Context, msg string, args ...any) {
+	logWithCaller(ctx, LevelCritical, msg, args...)
+}
+
+// Log logs at any level with ctx. Like the other *Context functions it logs
+// through the logger from FromContext. Fatal and panic levels are only logged,
+// use Fatal and Panic to also exit.
+func Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logWithCaller(ctx, level, msg, args...)
+}
+
+// LogAttrs is a more efficient version of Log that only accepts attributes
+func LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger := FromContext(ctx)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers and LogAttrs
+	runtime.Callers(2, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.AddAttrs(attrs...)
+	_ = logger.Handler().Handle(ctx, r)
 }
 
 // logWithCaller logs with the correct caller information
-func logWithCaller(level slog.Level, msg string, args ...any) {
-	ctx := context.TODO()
-	if !Get().Enabled(ctx, level) {
+func logWithCaller(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger := FromContext(ctx)
+	if !logger.Enabled(ctx, level) {
 		return
 	}
 
 	var pcs [1]uintptr
 	// Skip 2 frames to get the real caller:
 	// 1. this function (logWithCaller)
-	// 2. the wrapper function (Debug, Info, Warn, Error)
+	// 2. the wrapper function (Debug, Info, Warn, Error, ...)
 	runtime.Callers(3, pcs[:])
 
 	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
 	r.Add(args...)
 
-	_ = Get().Handler().Handle(ctx, r)
+	_ = logger.Handler().Handle(ctx, r)
+}
+
+// Tracef logs a formatted message at trace level
+func Tracef(format string, args ...any) {
+	logfWithCaller(context.Background(), LevelTrace, format, args...)
+}
+
+// Debugf logs a formatted message at debug level
+func Debugf(format string, args ...any) {
+	logfWithCaller(context.Background(), slog.LevelDebug, format, args...)
+}
+
+// Infof logs a formatted message at info level
+func Infof(format string, args ...any) {
+	logfWithCaller(context.Background(), slog.LevelInfo, format, args...)
+}
+
+// Noticef logs a formatted message at notice level
+func Noticef(format string, args ...any) {
+	logfWithCaller(context.Background(), LevelNotice, format, args...)
+}
+
+// Warnf logs a formatted message at warn level
+func Warnf(format string, args ...any) {
+	logfWithCaller(context.Background(), slog.LevelWarn, format, args...)
+}
+
+// Errorf logs a formatted message at error level
+func Errorf(format string, args ...any) {
+	logfWithCaller(context.Background(), slog.LevelError, format, args...)
+}
+
+// Criticalf logs a formatted message at critical level
+func Criticalf(format string, args ...any) {
+	logfWithCaller(context.Background(), LevelCritical, format, args...)
+}
+
+// Fatalf logs a formatted message at fatal level, runs the exit hooks and exits with status 1
+func Fatalf(format string, args ...any) {
+	logfWithCaller(context.Background(), LevelFatal, format, args...)
+	exit(1)
+}
+
+// Panicf logs a formatted message at panic level, runs the exit hooks and panics with it
+func Panicf(format string, args ...any) {
+	logfWithCaller(context.Background(), LevelPanic, format, args...)
+	runExitHooks()
+	panic(fmt.Sprintf(format, args[:fieldStart(format, args)]...))
+}
+
+// TraceContextf logs a formatted message at trace level with ctx
+func TraceContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, LevelTrace, format, args...)
+}
+
+// DebugContextf logs a formatted message at debug level with ctx
+func DebugContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, slog.LevelDebug, format, args...)
+}
+
+// InfoContextf logs a formatted message at info level with ctx
+func InfoContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, slog.LevelInfo, format, args...)
 }
 
-// ParseLevel converts a string to slog.Level
+// NoticeContextf logs a formatted message at notice level with ctx
+func NoticeContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, LevelNotice, format, args...)
+}
+
+// WarnContextf logs a formatted message at warn level with ctx
+func WarnContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, slog.LevelWarn, format, args...)
+}
+
+// ErrorContextf logs a formatted message at error level with ctx
+func ErrorContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, slog.LevelError, format, args...)
+}
+
+// CriticalContextf logs a formatted message at critical level with ctx
+func CriticalContextf(ctx context.Context, format string, args ...any) {
+	logfWithCaller(ctx, LevelCritical, format, args...)
+}
+
+// logfWithCaller is logWithCaller for format strings. The message is only
+// formatted if the level is enabled. Trailing slog.Attr arguments that format
+// has no verbs for are added as attributes.
+func logfWithCaller(ctx context.Context, level slog.Level, format string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger := FromContext(ctx)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers, this function and the wrapper (Debugf, Infof, ...)
+	runtime.Callers(3, pcs[:])
+
+	n := fieldStart(format, args)
+	r := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args[:n]...), pcs[0])
+	for _, arg := range args[n:] {
+		r.AddAttrs(arg.(slog.Attr))
+	}
+
+	_ = logger.Handler().Handle(ctx, r)
+}
+
+// fieldStart returns the index of the first of the trailing slog.Attr values
+// in args that format doesn't use, they are logged as attributes
+func fieldStart(format string, args []any) int {
+	operands := formatOperands(format)
+	n := len(args)
+	for n > operands {
+		if _, ok := args[n-1].(slog.Attr); !ok {
+			break
+		}
+		n--
+	}
+	return n
+}
+
+// formatOperands returns how many arguments format uses, following fmt's
+// rules for '*' widths and precisions and explicit indexes like %[2]d
+func formatOperands(format string) int {
+	used, next := 0, 0
+	consume := func() {
+		next++
+		used = max(used, next)
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) != -1 {
+			i++
+		}
+	spec:
+		for i < len(format) {
+			switch c := format[i]; {
+			case c == '[':
+				end := strings.IndexByte(format[i:], ']')
+				if end == -1 {
+					break spec
+				}
+				if index, err := strconv.Atoi(format[i+1 : i+end]); err == nil && index > 0 {
+					next = index - 1
+				}
+				i += end + 1
+			case c == '*':
+				consume()
+				i++
+			case c == '.' || '0' <= c && c <= '9':
+				i++
+			default:
+				break spec
+			}
+		}
+		if i < len(format) && format[i] != '%' {
+			consume()
+		}
+	}
+	return used
+}
+
+// ParseLevel converts a registered level name such as "debug" or "critical" to slog.Level,
+// unknown names fall back to info
 func ParseLevel(level string) slog.Level {
-	switch level {
-	case "debug":
-		return slog.LevelDebug
-	case "warn":
-		return slog.LevelWarn
-	case "error":
-		return slog.LevelError
-	default:
-		return slog.LevelInfo
+	if l, ok := LevelByName(level); ok {
+		return l
 	}
+	return slog.LevelInfo
 }
 
 // Attribute convenience functions for structured logging
@@ -127,4 +499,4 @@ func Duration(key string, value any) slog.Attr {
 
 func Any(key string, value any) slog.Attr {
 	return slog.Any(key, value)
-}
\ No newline at end of file
+}
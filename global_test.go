@@ -0,0 +1,200 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	logger := SetupPrettyLogger(&buf, slog.LevelInfo, false)
+	derived := logger.With("component", "cache").WithGroup("stats")
+
+	derived.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug to be filtered, got %q", buf.String())
+	}
+
+	SetLevel(slog.LevelDebug)
+	if GetLevel() != slog.LevelDebug {
+		t.Errorf("expected GetLevel to report debug, got %v", GetLevel())
+	}
+	derived.Debug("visible")
+	if !strings.Contains(buf.String(), "visible") {
+		t.Errorf("expected debug after SetLevel, got %q", buf.String())
+	}
+
+	buf.Reset()
+	SetLevel(slog.LevelError)
+	logger.Warn("hidden again")
+	if buf.Len() != 0 {
+		t.Errorf("expected warn to be filtered, got %q", buf.String())
+	}
+}
+
+func TestSetupLoggerLevels(t *testing.T) {
+	defer SetLevel(slog.LevelInfo)
+
+	var debug, warn bytes.Buffer
+	debugLogger := SetupPrettyLogger(&debug, slog.LevelDebug, false)
+	warnLogger := SetupPrettyJSONLogger(&warn, slog.LevelWarn, false)
+
+	debugLogger.Debug("debug visible")
+	warnLogger.Info("info hidden")
+	if !strings.Contains(debug.String(), "debug visible") {
+		t.Errorf("expected the first logger to keep its debug level, got %q", debug.String())
+	}
+	if warn.Len() != 0 {
+		t.Errorf("expected the second logger to filter info, got %q", warn.String())
+	}
+	if GetLevel() != slog.LevelInfo {
+		t.Errorf("expected Setup functions to leave the global level alone, got %v", GetLevel())
+	}
+
+	// SetLevel reaches both, and Leveler reports it
+	debug.Reset()
+	SetLevel(slog.LevelError)
+	if Leveler().Level() != slog.LevelError {
+		t.Errorf("expected Leveler to report error, got %v", Leveler().Level())
+	}
+	if _, ok := Leveler().(*slog.LevelVar); ok {
+		t.Error("expected Leveler to be read-only")
+	}
+	debugLogger.Warn("warn hidden")
+	warnLogger.Error("error visible")
+	if debug.Len() != 0 || !strings.Contains(warn.String(), "error visible") {
+		t.Errorf("expected both loggers to follow SetLevel, got %q and %q", debug.String(), warn.String())
+	}
+}
+
+func TestConfigureAndReset(t *testing.T) {
+	defer Reset()
+
+	var first, second bytes.Buffer
+	Configure(WithWriter(&first), WithFormat(FormatJSON))
+	InitGlobal(slog.LevelInfo, "text", false) // ignored, already configured
+	Info("one")
+	Configure(WithWriter(&second), WithFormat(FormatLogfmt))
+	Info("two")
+	slog.Info("three")
+
+	if !strings.Contains(first.String(), "one") || strings.Contains(first.String(), "two") {
+		t.Errorf("unexpected output of the first logger %q", first.String())
+	}
+	if !strings.Contains(second.String(), "two") || !strings.Contains(second.String(), "three") {
+		t.Errorf("expected the second logger to be the global and slog default, got %q", second.String())
+	}
+
+	SetLevel(slog.LevelError)
+	Reset()
+	if GetLevel() != slog.LevelInfo {
+		t.Errorf("expected Reset to restore the info level, got %v", GetLevel())
+	}
+	if globalLogger.Load() != nil {
+		t.Error("expected Reset to clear the global logger")
+	}
+}
+
+func TestGetConcurrent(t *testing.T) {
+	Reset()
+	defer Reset()
+	t.Setenv(OutputEnv, "stderr")
+	t.Setenv(LevelEnv, "error")
+
+	var wg sync.WaitGroup
+	loggers := make([]*slog.Logger, 8)
+	for i := range loggers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			loggers[i] = Get()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, logger := range loggers {
+		if logger != loggers[0] {
+			t.Fatal("expected every goroutine to get the same logger")
+		}
+	}
+}
+
+// countingStringer counts how often it is formatted
+type countingStringer struct{ calls *int }
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "bob"
+}
+
+func TestPrintfHelpers(t *testing.T) {
+	defer Reset()
+	Configure(WithWriter(io.Discard))
+
+	calls := 0
+	Debugf("user %s logged in", countingStringer{&calls})
+	if calls != 0 {
+		t.Fatalf("expected disabled levels not to format, got %d calls", calls)
+	}
+
+	rec := &recordingHandler{}
+	Configure(WithWriter(io.Discard), WithHandlers(rec))
+
+	Infof("user %s failed %d times", countingStringer{&calls}, 3, slog.String("ip", "10.0.0.1"))
+	WarnContextf(context.Background(), "retrying in %v", time.Second)
+	if len(rec.records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(rec.records))
+	}
+
+	r := rec.records[0]
+	if r.Message != "user bob failed 3 times" || r.NumAttrs() != 1 {
+		t.Errorf("unexpected record %q with %d attrs", r.Message, r.NumAttrs())
+	}
+	if rec.records[1].Message != "retrying in 1s" {
+		t.Errorf("unexpected message %q", rec.records[1].Message)
+	}
+	for _, r := range rec.records {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if !strings.HasSuffix(f.Function, ".TestPrintfHelpers") {
+			t.Errorf("expected the caller to be the test, got %s", f.Function)
+		}
+	}
+
+	// An attribute a verb needs is formatted, only the ones after it are fields
+	rec.records = nil
+	Infof("value is %v", slog.Int("x", 1), slog.Int("y", 2))
+	if r := rec.records[0]; r.Message != "value is x=1" || r.NumAttrs() != 1 {
+		t.Errorf("unexpected record %q with %d attrs", r.Message, r.NumAttrs())
+	}
+}
+
+func TestFormatOperands(t *testing.T) {
+	testCases := map[string]int{
+		"no verbs":         0,
+		"100%% done":       0,
+		"%s failed %d":     2,
+		"%-8s|%6.2f":       2,
+		"%*d":              2,
+		"%.*f":             2,
+		"%[2]s %[1]s":      2,
+		"%[3]v then %v":    4,
+		"%v %%":            1,
+		"trailing %":       0,
+		"%x, then %+q ✓ %": 2,
+	}
+
+	for format, want := range testCases {
+		if got := formatOperands(format); got != want {
+			t.Errorf("formatOperands(%q) = %d, want %d", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package mojilog
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+)
+
+// Extra levels, spaced around slog's own levels
+const (
+	LevelTrace    slog.Level = -8
+	LevelNotice   slog.Level = 2
+	LevelCritical slog.Level = 12
+	LevelFatal    slog.Level = 16
+	LevelPanic    slog.Level = 20
+)
+
+// LevelSpec describes how a level is named and rendered
+type LevelSpec struct {
+	// Level is the numeric slog level
+	Level slog.Level
+	// Name is the full name used in JSON output and by ParseLevel, e.g. "CRITICAL"
+	Name string
+	// Label is the short name shown by PrettyHandler, e.g. "CRIT".
+	// Labels are right-aligned to the widest registered label. Defaults to Name.
+	Label string
+	// Color is used for the label and for PrettyJSONHandler output
+	Color []color.Attribute
+	// Category picks the level glyph from the theme
+	Category Category
+	// Emoji is used when the theme has no glyph for Category
+	Emoji string
+}
+
+// levelSnapshot is an immutable view of the registered levels
+type levelSnapshot struct {
+	specs  []LevelSpec    // sorted by level
+	colors []*color.Color // parallel to specs
+	width  int            // display width of the widest label
+}
+
+func newLevelSnapshot(specs []LevelSpec) *levelSnapshot {
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Level < specs[j].Level })
+	snap := &levelSnapshot{specs: specs, colors: make([]*color.Color, len(specs))}
+	for i, spec := range specs {
+		snap.colors[i] = color.New(spec.Color...)
+		if w := runewidth.StringWidth(spec.Label); w > snap.width {
+			snap.width = w
+		}
+	}
+	return snap
+}
+
+// find returns the index of the highest registered level at or below level,
+// or the lowest level if level is below all of them
+func (snap *levelSnapshot) find(level slog.Level) int {
+	i := sort.Search(len(snap.specs), func(i int) bool { return snap.specs[i].Level > level })
+	if i == 0 {
+		return 0
+	}
+	return i - 1
+}
+
+var (
+	levelsMu sync.Mutex // serializes writers
+	levels   atomic.Pointer[levelSnapshot]
+)
+
+func init() {
+	levels.Store(newLevelSnapshot(append([]LevelSpec(nil), defaultLevelSpecs...)))
+}
+
+// defaultLevelSpecs are the levels registered out of the box
+var defaultLevelSpecs = []LevelSpec{
+	{Level: LevelTrace, Name: "TRACE", Label: "TRACE", Color: []color.Attribute{color.FgHiBlack}, Category: CategoryTrace},
+	{Level: slog.LevelDebug, Name: "DEBUG", Label: "DEBUG", Color: []color.Attribute{color.FgCyan}, Category: CategoryDebug},
+	{Level: slog.LevelInfo, Name: "INFO", Label: "INFO", Color: []color.Attribute{color.FgGreen}, Category: CategoryInfo},
+	{Level: LevelNotice, Name: "NOTICE", Label: "NOTE", Color: []color.Attribute{color.FgHiGreen, color.Bold}, Category: CategoryNotice},
+	{Level: slog.LevelWarn, Name: "WARN", Label: "WARN", Color: []color.Attribute{color.FgYellow}, Category: CategoryWarn},
+	{Level: slog.LevelError, Name: "ERROR", Label: "ERROR", Color: []color.Attribute{color.FgRed, color.Bold}, Category: CategoryError},
+	{Level: LevelCritical, Name: "CRITICAL", Label: "CRIT", Color: []color.Attribute{color.FgHiRed, color.Bold, color.Underline}, Category: CategoryCritical},
+	{Level: LevelFatal, Name: "FATAL", Label: "FATAL", Color: []color.Attribute{color.FgRed, color.Bold, color.BgWhite}, Category: CategoryFatal},
+	{Level: LevelPanic, Name: "PANIC", Label: "PANIC", Color: []color.Attribute{color.FgMagenta, color.Bold, color.BgWhite}, Category: CategoryFatal},
+}
+
+// RegisterLevel adds a level to the registry, or updates the level with the same value
+func RegisterLevel(spec LevelSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("level %d: missing name", int(spec.Level))
+	}
+	if spec.Label == "" {
+		spec.Label = spec.Name
+	}
+
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+
+	specs := append([]LevelSpec(nil), levels.Load().specs...)
+	replaced := false
+	for i := range specs {
+		if specs[i].Level == spec.Level {
+			specs[i] = spec
+			replaced = true
+		} else if strings.EqualFold(specs[i].Name, spec.Name) {
+			return fmt.Errorf("level %q: already registered as %d", spec.Name, int(specs[i].Level))
+		}
+	}
+	if !replaced {
+		specs = append(specs, spec)
+	}
+	levels.Store(newLevelSnapshot(specs))
+	return nil
+}
+
+// RegisteredLevels returns the registered levels, lowest first
+func RegisteredLevels() []LevelSpec {
+	return append([]LevelSpec(nil), levels.Load().specs...)
+}
+
+// LevelSpecFor returns the spec of the highest registered level at or below level
+func LevelSpecFor(level slog.Level) LevelSpec {
+	snap := levels.Load()
+	return snap.specs[snap.find(level)]
+}
+
+// LevelName returns the registered name of a level. Levels between two
+// registered ones are written like slog does, e.g. "INFO+1".
+func LevelName(level slog.Level) string {
+	spec := LevelSpecFor(level)
+	switch {
+	case level == spec.Level:
+		return spec.Name
+	case level > spec.Level:
+		return fmt.Sprintf("%s+%d", spec.Name, int(level-spec.Level))
+	default:
+		return fmt.Sprintf("%s%d", spec.Name, int(level-spec.Level))
+	}
+}
+
+// LevelByName looks a level up by its name or label, ignoring case
+func LevelByName(name string) (slog.Level, bool) {
+	name = strings.TrimSpace(name)
+	for _, spec := range levels.Load().specs {
+		if strings.EqualFold(spec.Name, name) || strings.EqualFold(spec.Label, name) {
+			return spec.Level, true
+		}
+	}
+	return 0, false
+}
+
+// levelLabel returns the colored, right-aligned label of a level
+func levelLabel(level slog.Level, mode ColorMode) string {
+	snap := levels.Load()
+	i := snap.find(level)
+	label := snap.specs[i].Label
+	if pad := snap.width - runewidth.StringWidth(label); pad > 0 {
+		label = strings.Repeat(" ", pad) + label
+	}
+	return paint(mode, snap.colors[i], label)
+}
+
+// levelColor returns the color of a level
+func levelColor(level slog.Level) *color.Color {
+	snap := levels.Load()
+	return snap.colors[snap.find(level)]
+}
+
+// levelGlyph returns the theme glyph of a level
+func levelGlyph(theme *Theme, level slog.Level) string {
+	spec := LevelSpecFor(level)
+	if glyph, ok := theme.Glyphs[spec.Category]; ok {
+		return glyph
+	}
+	if theme.AllowLiteral {
+		return spec.Emoji
+	}
+	return ""
+}
+
+// levelReplaceAttr writes registered level names for slog's built-in handlers
+func levelReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(LevelName(level))
+		}
+	}
+	return a
+}
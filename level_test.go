@@ -0,0 +1,112 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestLevelNames(t *testing.T) {
+	testCases := []struct {
+		level    slog.Level
+		expected string
+	}{
+		{LevelTrace, "TRACE"},
+		{slog.LevelDebug, "DEBUG"},
+		{LevelNotice, "NOTICE"},
+		{LevelCritical, "CRITICAL"},
+		{LevelFatal, "FATAL"},
+		{slog.LevelInfo + 1, "INFO+1"},
+		{LevelTrace - 2, "TRACE-2"},
+	}
+
+	for _, tc := range testCases {
+		if got := LevelName(tc.level); got != tc.expected {
+			t.Errorf("LevelName(%d): expected %q, got %q", tc.level, tc.expected, got)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	testCases := map[string]slog.Level{
+		"trace":    LevelTrace,
+		"DEBUG":    slog.LevelDebug,
+		"notice":   LevelNotice,
+		"warn":     slog.LevelWarn,
+		"critical": LevelCritical,
+		"crit":     LevelCritical,
+		"fatal":    LevelFatal,
+		"bogus":    slog.LevelInfo,
+	}
+
+	for name, expected := range testCases {
+		if got := ParseLevel(name); got != expected {
+			t.Errorf("ParseLevel(%q): expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	defer levels.Store(newLevelSnapshot(append([]LevelSpec(nil), defaultLevelSpecs...)))
+
+	err := RegisterLevel(LevelSpec{
+		Level:    slog.Level(6),
+		Name:     "AUDIT",
+		Color:    []color.Attribute{color.FgBlue},
+		Category: "audit",
+		Emoji:    "🧾",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterLevel(LevelSpec{Level: slog.Level(7), Name: "audit"}); err == nil {
+		t.Error("expected duplicate level name to be rejected")
+	}
+
+	var buf bytes.Buffer
+	handler := NewPrettyHandler(&buf, nil)
+	handler.SetTheme(ThemeUnicode)
+	slog.New(handler).Log(context.Background(), slog.Level(6), "invoice sent")
+
+	if !strings.Contains(buf.String(), "AUDIT") || !strings.Contains(buf.String(), "🧾") {
+		t.Errorf("expected custom level label and emoji, got %q", buf.String())
+	}
+
+	buf.Reset()
+	SetupLogger(&buf, slog.LevelInfo, "json", false).Log(context.Background(), slog.Level(6), "invoice sent")
+	if !strings.Contains(buf.String(), `"level":"AUDIT"`) {
+		t.Errorf("expected custom level name in JSON output, got %q", buf.String())
+	}
+}
+
+func TestPrettyLevelLabelsAligned(t *testing.T) {
+	width := len(stripANSI(levelLabel(slog.LevelInfo, ColorNever)))
+	for _, spec := range RegisteredLevels() {
+		label := stripANSI(levelLabel(spec.Level, ColorAuto))
+		if !strings.HasSuffix(label, spec.Label) {
+			t.Errorf("label %q does not end with %q", label, spec.Label)
+		}
+		if len(label) != width {
+			t.Errorf("label %q has width %d, expected %d", label, len(label), width)
+		}
+	}
+}
+
+// stripANSI removes color escape sequences
+func stripANSI(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
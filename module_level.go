@@ -0,0 +1,104 @@
+package mojilog
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// moduleLevel overrides the global level for the packages or functions matching a glob pattern
+type moduleLevel struct {
+	pattern string
+	level   slog.Level
+}
+
+var (
+	moduleMu     sync.Mutex // serializes writers
+	moduleLevels atomic.Pointer[[]moduleLevel]
+	moduleGen    atomic.Uint64 // bumped on every change so cached lookups can be invalidated
+)
+
+// SetModuleLevel sets the level for the packages or functions matching pattern,
+// e.g. "myapp/internal/cache/*". When several patterns match, the most specific
+// one (the longest before its first wildcard) wins.
+func SetModuleLevel(pattern string, level slog.Level) {
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+
+	levels := currentModuleLevels()
+	for i := range levels {
+		if levels[i].pattern == pattern {
+			levels[i].level = level
+			storeModuleLevels(levels)
+			return
+		}
+	}
+	storeModuleLevels(append(levels, moduleLevel{pattern: pattern, level: level}))
+}
+
+// ClearModuleLevel removes the override for pattern and reports whether it existed
+func ClearModuleLevel(pattern string) bool {
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+
+	levels := currentModuleLevels()
+	for i := range levels {
+		if levels[i].pattern == pattern {
+			storeModuleLevels(append(levels[:i], levels[i+1:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// ModuleLevels returns the per-module level overrides by pattern
+func ModuleLevels() map[string]slog.Level {
+	levels := make(map[string]slog.Level)
+	if p := moduleLevels.Load(); p != nil {
+		for _, ml := range *p {
+			levels[ml.pattern] = ml.level
+		}
+	}
+	return levels
+}
+
+// ModuleLevel returns the level override for pattern, if there is one
+func ModuleLevel(pattern string) (slog.Level, bool) {
+	if p := moduleLevels.Load(); p != nil {
+		for _, ml := range *p {
+			if ml.pattern == pattern {
+				return ml.level, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// currentModuleLevels returns a private copy of the overrides, the caller must hold moduleMu
+func currentModuleLevels() []moduleLevel {
+	p := moduleLevels.Load()
+	if p == nil {
+		return nil
+	}
+	return append([]moduleLevel(nil), (*p)...)
+}
+
+// storeModuleLevels orders the overrides most specific first and publishes them
+func storeModuleLevels(levels []moduleLevel) {
+	sort.SliceStable(levels, func(i, j int) bool {
+		return patternSpecificity(levels[i].pattern) > patternSpecificity(levels[j].pattern)
+	})
+	moduleLevels.Store(&levels)
+	moduleGen.Add(1)
+}
+
+// patternSpecificity is the length of the literal prefix of a glob pattern
+func patternSpecificity(pattern string) int {
+	if i := strings.IndexAny(pattern, "*?"); i != -1 {
+		return i
+	}
+	// Exact patterns beat any wildcard pattern with the same prefix
+	return len(pattern) + 1
+}
@@ -0,0 +1,371 @@
+package mojilog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Format selects the output format of New
+type Format string
+
+const (
+	// FormatPretty is colored text for humans (default)
+	FormatPretty Format = "pretty"
+	// FormatPrettyJSON is colored, indented JSON
+	FormatPrettyJSON Format = "pretty-json"
+	// FormatJSON is slog's JSON with emojis, for machines
+	FormatJSON Format = "json"
+	// FormatLogfmt is slog's key=value text with emojis
+	FormatLogfmt Format = "logfmt"
+)
+
+// ParseFormat converts a format name to a Format. "text" is accepted for FormatPretty.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatPretty, FormatPrettyJSON, FormatJSON, FormatLogfmt:
+		return f, nil
+	case "text":
+		return FormatPretty, nil
+	}
+	return FormatPretty, fmt.Errorf("unknown format %q (want pretty, pretty-json, json or logfmt)", s)
+}
+
+// Options configures the handler built by New. The zero value logs pretty text
+// to stdout at the shared global level.
+type Options struct {
+	// Writer receives the output, defaults to os.Stdout
+	Writer io.Writer
+	// Level is the minimum level, defaults to the shared level changed by SetLevel
+	Level slog.Leveler
+	// Format defaults to FormatPretty
+	Format Format
+	// AddSource adds the caller's file, function and line
+	AddSource bool
+	// TimeFormat is the timestamp layout. Defaults to DefaultPrettyTimeFormat and
+	// DefaultPrettyJSONTimeFormat for the pretty formats and RFC 3339 for the others.
+	TimeFormat string
+	// Location is the time zone of timestamps, defaults to time.Local
+	Location *time.Location
+	// Theme picks the glyphs, nil follows DefaultTheme
+	Theme *Theme
+	// Filter selects the attributes to show, the zero value shows every attribute
+	Filter AttrFilter
+	// Color controls ANSI colors of the pretty formats
+	Color ColorMode
+	// EmojiRules pick contextual emojis, nil uses DefaultEmojiRules
+	EmojiRules *EmojiRuleSet
+	// EmojiPlacement controls where the emoji goes in FormatJSON and FormatLogfmt,
+	// EmojiOff turns emojis off in every format
+	EmojiPlacement EmojiPlacement
+	// EmojiKey is the attribute key used by EmojiAttr placement, defaults to DefaultEmojiKey
+	EmojiKey string
+	// ReplaceAttr rewrites or removes attributes, including the built-in time,
+	// level, msg and source, in every format. See slog.HandlerOptions.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+	// OmitTrace leaves out the trace and span IDs of OpenTelemetry spans, see OTelExtractor
+	OmitTrace bool
+	// ContextExtractors add attributes from the context of every record, see ContextHandler
+	ContextExtractors []ContextExtractor
+	// Handlers also receive every record, e.g. to ship logs elsewhere.
+	// They filter by their own levels.
+	Handlers []slog.Handler
+}
+
+// Option changes one setting of Options
+type Option func(*Options)
+
+// WithOptions replaces all settings with o
+func WithOptions(o Options) Option {
+	return func(opts *Options) { *opts = o }
+}
+
+// WithWriter sets the output writer
+func WithWriter(w io.Writer) Option {
+	return func(o *Options) { o.Writer = w }
+}
+
+// WithLevel sets the minimum level, e.g. slog.LevelDebug or a *slog.LevelVar
+func WithLevel(level slog.Leveler) Option {
+	return func(o *Options) { o.Level = level }
+}
+
+// WithFormat sets the output format
+func WithFormat(format Format) Option {
+	return func(o *Options) { o.Format = format }
+}
+
+// WithSource adds the caller's file, function and line
+func WithSource(addSource bool) Option {
+	return func(o *Options) { o.AddSource = addSource }
+}
+
+// WithTimeFormat sets the timestamp layout
+func WithTimeFormat(layout string) Option {
+	return func(o *Options) { o.TimeFormat = layout }
+}
+
+// WithLocation sets the time zone of timestamps
+func WithLocation(loc *time.Location) Option {
+	return func(o *Options) { o.Location = loc }
+}
+
+// WithTheme sets the glyph theme
+func WithTheme(theme *Theme) Option {
+	return func(o *Options) { o.Theme = theme }
+}
+
+// WithAttrFilter sets the filter that selects the attributes to show
+func WithAttrFilter(filter AttrFilter) Option {
+	return func(o *Options) { o.Filter = filter }
+}
+
+// WithSkipKeys leaves out the attributes at the given dotted paths, see SkipKeys
+func WithSkipKeys(keys ...string) Option {
+	return WithAttrFilter(SkipKeys(keys...))
+}
+
+// WithOnlyKeys shows only the attributes at the given dotted paths, see OnlyKeys
+func WithOnlyKeys(keys ...string) Option {
+	return WithAttrFilter(OnlyKeys(keys...))
+}
+
+// WithColor sets the color mode
+func WithColor(mode ColorMode) Option {
+	return func(o *Options) { o.Color = mode }
+}
+
+// WithEmojiRules sets the rules that pick contextual emojis
+func WithEmojiRules(rules *EmojiRuleSet) Option {
+	return func(o *Options) { o.EmojiRules = rules }
+}
+
+// WithEmojiPlacement sets where the emoji goes
+func WithEmojiPlacement(placement EmojiPlacement) Option {
+	return func(o *Options) { o.EmojiPlacement = placement }
+}
+
+// WithEmojiKey sets the attribute key used by EmojiAttr placement
+func WithEmojiKey(key string) Option {
+	return func(o *Options) { o.EmojiKey = key }
+}
+
+// WithReplaceAttr sets the function that rewrites or removes attributes
+func WithReplaceAttr(fn func(groups []string, a slog.Attr) slog.Attr) Option {
+	return func(o *Options) { o.ReplaceAttr = fn }
+}
+
+// WithTrace adds the trace and span IDs of OpenTelemetry spans, on by default
+func WithTrace(enabled bool) Option {
+	return func(o *Options) { o.OmitTrace = !enabled }
+}
+
+// WithContextExtractors adds extractors that enrich every record from its context
+func WithContextExtractors(extractors ...ContextExtractor) Option {
+	return func(o *Options) { o.ContextExtractors = append(o.ContextExtractors, extractors...) }
+}
+
+// WithHandlers adds handlers that also receive every record
+func WithHandlers(handlers ...slog.Handler) Option {
+	return func(o *Options) { o.Handlers = append(o.Handlers, handlers...) }
+}
+
+// New creates a logger from the given options
+func New(opts ...Option) *slog.Logger {
+	return slog.New(NewHandler(opts...))
+}
+
+// NewHandler creates the handler used by New. It applies the module levels
+// from SetModuleLevel and SetVModule.
+func NewHandler(opts ...Option) slog.Handler {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.handler()
+}
+
+// handler builds the handler described by o
+func (o Options) handler() slog.Handler {
+	w := o.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	level := o.Level
+	if level == nil {
+		level = globalLevel
+	}
+	rules := o.EmojiRules
+	if rules == nil {
+		rules = DefaultEmojiRules()
+	}
+	handlerOpts := &slog.HandlerOptions{
+		Level:       level,
+		AddSource:   o.AddSource,
+		ReplaceAttr: o.ReplaceAttr,
+	}
+
+	var h slog.Handler
+	switch o.Format {
+	case FormatJSON, FormatLogfmt:
+		handlerOpts.ReplaceAttr = o.builtinReplaceAttr()
+		var base slog.Handler
+		if o.Format == FormatJSON {
+			base = slog.NewJSONHandler(w, handlerOpts)
+		} else {
+			base = slog.NewTextHandler(w, handlerOpts)
+		}
+		eh := NewEmojiHandler(base)
+		eh.SetEmojiRules(rules)
+		eh.SetTheme(o.Theme)
+		eh.SetPlacement(o.EmojiPlacement)
+		if o.EmojiKey != "" {
+			eh.SetEmojiKey(o.EmojiKey)
+		}
+		h = eh
+	case FormatPrettyJSON:
+		ph := NewPrettyJSONHandler(w, handlerOpts)
+		ph.SetEmojiRules(rules)
+		ph.SetTheme(o.Theme)
+		ph.SetShowEmoji(o.EmojiPlacement != EmojiOff)
+		ph.SetColorMode(o.Color)
+		ph.SetLocation(o.Location)
+		if o.TimeFormat != "" {
+			ph.SetTimeFormat(o.TimeFormat)
+		}
+		ph.SetAttrFilter(o.Filter)
+		h = ph
+	default:
+		ph := NewPrettyHandler(w, handlerOpts)
+		ph.SetEmojiRules(rules)
+		ph.SetTheme(o.Theme)
+		ph.SetShowEmoji(o.EmojiPlacement != EmojiOff)
+		ph.SetShowTrace(!o.OmitTrace)
+		ph.SetColorMode(o.Color)
+		ph.SetLocation(o.Location)
+		if o.TimeFormat != "" {
+			ph.SetTimeFormat(o.TimeFormat)
+		}
+		ph.SetAttrFilter(o.Filter)
+		h = ph
+	}
+
+	h = NewVModuleHandler(h)
+	if len(o.Handlers) > 0 {
+		handlers := []slog.Handler{topLevel(h)}
+		for _, handler := range o.Handlers {
+			handlers = append(handlers, topLevel(handler))
+		}
+		h = &fanoutHandler{handlers: handlers}
+	}
+	extractors := o.ContextExtractors
+	if !o.OmitTrace {
+		extractors = append([]ContextExtractor{OTelExtractor}, extractors...)
+	}
+	if len(extractors) > 0 {
+		h = NewContextHandler(h, extractors...)
+	}
+	return h
+}
+
+// builtinReplaceAttr adds registered level names, the time format and zone
+// and the attribute filter to the user's ReplaceAttr for slog's own handlers
+func (o Options) builtinReplaceAttr() func(groups []string, a slog.Attr) slog.Attr {
+	replace, timeFormat, loc, filter := o.ReplaceAttr, o.TimeFormat, o.Location, o.Filter
+	emojiKey := ""
+	if o.EmojiPlacement == EmojiAttr {
+		emojiKey = o.EmojiKey
+		if emojiKey == "" {
+			emojiKey = DefaultEmojiKey
+		}
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		builtin := false
+		if len(groups) == 0 {
+			switch a.Key {
+			case slog.TimeKey:
+				builtin = true
+				if a.Value.Kind() != slog.KindTime {
+					break
+				}
+				t := a.Value.Time()
+				if loc != nil {
+					t = t.In(loc)
+				}
+				if timeFormat != "" {
+					a.Value = slog.StringValue(t.Format(timeFormat))
+				} else {
+					a.Value = slog.TimeValue(t)
+				}
+			case slog.LevelKey, slog.MessageKey, slog.SourceKey:
+				builtin = true
+			}
+		}
+		// slog passes group members one by one, so filtering them filters their groups.
+		// The emoji added by EmojiHandler is not filtered either.
+		if len(groups) == 0 && a.Key == emojiKey {
+			builtin = true
+		}
+		if !builtin && !filter.keeps(attrPath(groups, a.Key)) {
+			return slog.Attr{}
+		}
+		if replace != nil {
+			a = replace(groups, a)
+		}
+		return levelReplaceAttr(groups, a)
+	}
+}
+
+// fanoutHandler sends records to several handlers, which must log the
+// attributes of withTopAttrs, see topLevel
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *fanoutHandler) handlesTopAttrs() bool { return true }
+
+// Enabled implements slog.Handler
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, r.Level) {
+			if err := handler.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// WithGroup implements slog.Handler
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
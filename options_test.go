@@ -0,0 +1,90 @@
+package mojilog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(
+		WithWriter(&buf),
+		WithLevel(slog.LevelDebug),
+		WithColor(ColorNever),
+		WithTimeFormat("[15:04]"),
+		WithSkipKeys("secret"),
+		WithTheme(ThemeASCII),
+	)
+
+	logger.Debug("cache warmed", "secret", "hunter2", "keys", 3)
+	out := buf.String()
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("expected no colors, got %q", out)
+	}
+	if !strings.HasPrefix(out, "[") || !strings.Contains(out, "DEBUG  [d] cache warmed keys=3") {
+		t.Errorf("unexpected output %q", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("expected skipped key to be left out, got %q", out)
+	}
+
+	buf.Reset()
+	New(WithWriter(&buf), WithColor(ColorAlways)).Info("colored")
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected colors, got %q", buf.String())
+	}
+}
+
+func TestNewFormats(t *testing.T) {
+	var buf bytes.Buffer
+	New(WithWriter(&buf), WithFormat(FormatJSON), WithEmojiPlacement(EmojiAttr), WithTimeFormat("2006")).
+		Info("Starting application", "pid", 42)
+	out := buf.String()
+	for _, want := range []string{`"msg":"Starting application"`, `"emoji":"🚀"`, `"pid":42`, `"time":"20`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+
+	buf.Reset()
+	New(WithWriter(&buf), WithFormat(FormatPrettyJSON), WithColor(ColorNever), WithEmojiPlacement(EmojiOff)).
+		Info("Starting application", "pid", 42)
+	out = buf.String()
+	if strings.Contains(out, "emoji") || !strings.Contains(out, `"pid": 42`) {
+		t.Errorf("expected no emoji and every attribute, got %s", out)
+	}
+}
+
+func TestNewHandlers(t *testing.T) {
+	var main, extra bytes.Buffer
+	logger := New(
+		WithWriter(&main),
+		WithLevel(slog.LevelWarn),
+		WithHandlers(slog.NewJSONHandler(&extra, &slog.HandlerOptions{Level: slog.LevelInfo})),
+	)
+
+	logger.With("request_id", "r1").Info("only shipped")
+	if main.Len() != 0 {
+		t.Errorf("expected the main handler to filter info, got %q", main.String())
+	}
+	if !strings.Contains(extra.String(), `"request_id":"r1"`) {
+		t.Errorf("expected the extra handler to get the record, got %q", extra.String())
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	if f, err := ParseFormat("text"); err != nil || f != FormatPretty {
+		t.Errorf("ParseFormat(text): got %v %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if m, err := ParseColorMode("always"); err != nil || m != ColorAlways {
+		t.Errorf("ParseColorMode(always): got %v %v", m, err)
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("expected an error for an unknown color mode")
+	}
+}
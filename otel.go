@@ -0,0 +1,54 @@
+package mojilog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/fatih/color"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys added by OTelExtractor
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// OTelExtractor adds the trace and span IDs of the OpenTelemetry span in the context.
+// New adds it unless Options.OmitTrace is set.
+func OTelExtractor(ctx context.Context) []slog.Attr {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []slog.Attr{
+		slog.String(TraceIDKey, sc.TraceID().String()),
+		slog.String(SpanIDKey, sc.SpanID().String()),
+	}
+}
+
+// traceColors are cycled through by trace ID so lines of one request share a color
+var traceColors = []*color.Color{
+	color.New(color.FgHiCyan),
+	color.New(color.FgHiMagenta),
+	color.New(color.FgHiYellow),
+	color.New(color.FgHiGreen),
+	color.New(color.FgHiBlue),
+	color.New(color.FgCyan),
+	color.New(color.FgMagenta),
+	color.New(color.FgYellow),
+}
+
+// tracePrefixLen is the number of hex digits of the trace ID shown by PrettyHandler
+const tracePrefixLen = 8
+
+// tracePrefix returns the short, colored trace ID of the span in the context, if any
+func tracePrefix(ctx context.Context, mode ColorMode) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	id := sc.TraceID()
+	c := traceColors[int(id[len(id)-1])%len(traceColors)]
+	return paint(mode, c, "["+id.String()[:tracePrefixLen]+"]")
+}
@@ -0,0 +1,69 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTelTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	ctx, span := provider.Tracer("mojilog").Start(context.Background(), "checkout")
+	defer span.End()
+	traceID := span.SpanContext().TraceID().String()
+	spanID := span.SpanContext().SpanID().String()
+
+	var buf bytes.Buffer
+	New(WithWriter(&buf), WithFormat(FormatJSON)).InfoContext(ctx, "Starting checkout")
+	for _, want := range []string{`"trace_id":"` + traceID + `"`, `"span_id":"` + spanID + `"`, "🚀"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("json: expected %s in %s", want, buf.String())
+		}
+	}
+
+	// The IDs stay at the top level so backends can correlate on them
+	buf.Reset()
+	New(WithWriter(&buf), WithFormat(FormatJSON)).With("service", "shop").WithGroup("http").
+		InfoContext(ctx, "request done", "status", 200)
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	http, _ := entry["http"].(map[string]any)
+	if entry["trace_id"] != traceID || entry["span_id"] != spanID || entry["service"] != "shop" {
+		t.Errorf("grouped json: expected top-level trace and span IDs, got %s", buf.String())
+	}
+	if len(http) != 1 || http["status"] != 200.0 {
+		t.Errorf("grouped json: expected only the status in the group, got %s", buf.String())
+	}
+
+	buf.Reset()
+	New(WithWriter(&buf), WithColor(ColorNever)).WithGroup("req").InfoContext(ctx, "Starting checkout")
+	out := buf.String()
+	if !strings.Contains(out, "INFO ["+traceID[:tracePrefixLen]+"]") {
+		t.Errorf("pretty: expected a short trace prefix in %q", out)
+	}
+	if !strings.Contains(out, " span_id="+spanID) || strings.Contains(out, "req.span_id") {
+		t.Errorf("pretty: expected the span ID in %q", out)
+	}
+
+	buf.Reset()
+	New(WithWriter(&buf), WithColor(ColorNever), WithTrace(false)).InfoContext(ctx, "Starting checkout")
+	if strings.Contains(buf.String(), traceID[:tracePrefixLen]) {
+		t.Errorf("expected no trace IDs with WithTrace(false), got %q", buf.String())
+	}
+
+	buf.Reset()
+	New(WithWriter(&buf), WithFormat(FormatJSON)).Info("no span")
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Errorf("expected no trace IDs without a span, got %q", buf.String())
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"io"
 	"log/slog"
 	"path/filepath"
-	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/fatih/color"
 )
@@ -16,40 +18,98 @@ import (
 // PrettyHandler is a custom handler that formats logs in a pretty way with colors
 type PrettyHandler struct {
 	opts      *slog.HandlerOptions
-	mu        sync.Mutex
+	mu        *sync.Mutex // shared with the handlers from WithAttrs and WithGroup
 	out       io.Writer
-	attrs     []slog.Attr
+	attrs     []slog.Attr // qualified with the groups open when they were added
+	formatted prettyAttrs // attrs formatted for the line
 	groups    []string
+	prefix    string // groups joined with dots, e.g. "http.request."
 	showEmoji bool
+	showTrace bool
+	rules     *EmojiRuleSet
+	theme     *Theme
+
+	timeFormat string
+	location   *time.Location
+	colors     ColorMode
+	filter     AttrFilter
 }
 
-// Color functions for different levels
+// Colors for the parts of a line, level colors come from the level registry
 var (
-	debugColor = color.New(color.FgCyan).SprintFunc()
-	infoColor  = color.New(color.FgGreen).SprintFunc()
-	warnColor  = color.New(color.FgYellow).SprintFunc()
-	errorColor = color.New(color.FgRed, color.Bold).SprintFunc()
-	fatalColor = color.New(color.FgRed, color.Bold, color.BgWhite).SprintFunc()
-
-	timeColor     = color.New(color.FgHiBlack).SprintFunc()
-	fileColor     = color.New(color.FgBlue).SprintFunc()
-	functionColor = color.New(color.FgBlue).SprintFunc()
-	attrColor     = color.New(color.FgMagenta).SprintFunc()
-	resetColor    = color.New(color.Reset).SprintFunc()
+	timeColor     = color.New(color.FgHiBlack)
+	fileColor     = color.New(color.FgBlue)
+	functionColor = color.New(color.FgBlue)
+	attrColor     = color.New(color.FgMagenta)
 )
 
+// DefaultPrettyTimeFormat is the timestamp layout of PrettyHandler
+const DefaultPrettyTimeFormat = "15:04:05.0"
+
 // NewPrettyHandler creates a new pretty handler
 func NewPrettyHandler(out io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
 	if opts == nil {
 		opts = &slog.HandlerOptions{}
 	}
 	return &PrettyHandler{
-		out:       out,
-		opts:      opts,
-		showEmoji: true,
+		mu:         new(sync.Mutex),
+		out:        out,
+		opts:       opts,
+		showEmoji:  true,
+		showTrace:  true,
+		rules:      DefaultEmojiRules(),
+		timeFormat: DefaultPrettyTimeFormat,
 	}
 }
 
+// SetEmojiRules sets the rules used to pick contextual emojis, nil disables them
+func (h *PrettyHandler) SetEmojiRules(rules *EmojiRuleSet) {
+	h.rules = rules
+}
+
+// SetTheme sets the glyph theme, nil follows DefaultTheme
+func (h *PrettyHandler) SetTheme(theme *Theme) {
+	h.theme = theme
+}
+
+// SetShowEmoji turns the emoji before the message on or off
+func (h *PrettyHandler) SetShowEmoji(show bool) {
+	h.showEmoji = show
+}
+
+// SetShowTrace turns the short trace ID of OpenTelemetry spans on or off
+func (h *PrettyHandler) SetShowTrace(show bool) {
+	h.showTrace = show
+}
+
+// SetTimeFormat sets the timestamp layout, see time.Layout
+func (h *PrettyHandler) SetTimeFormat(layout string) {
+	h.timeFormat = layout
+}
+
+// SetLocation sets the time zone of timestamps, nil uses time.Local
+func (h *PrettyHandler) SetLocation(loc *time.Location) {
+	h.location = loc
+}
+
+// SetColorMode sets whether output is colored, see ColorMode
+func (h *PrettyHandler) SetColorMode(mode ColorMode) {
+	h.colors = mode
+}
+
+// SetAttrFilter sets which attributes are shown, see AttrFilter
+func (h *PrettyHandler) SetAttrFilter(filter AttrFilter) {
+	h.filter = filter
+}
+
+// SetSkipKeys leaves out the attributes at the given paths, see SkipKeys
+func (h *PrettyHandler) SetSkipKeys(keys []string) {
+	h.filter = SkipKeys(keys...)
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *PrettyHandler) handlesTopAttrs() bool { return true }
+
 // Enabled implements slog.Handler
 func (h *PrettyHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	minLevel := slog.LevelInfo
@@ -64,44 +124,59 @@ func (h *PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	// Use local time (which is set to KST via TZ environment variable)
-	localTime := r.Time.Local()
+	// Built-in attributes go through ReplaceAttr like in slog's handlers,
+	// removing one leaves it out of the line
+	rep := h.opts.ReplaceAttr
 
-	// Format timestamp (short format)
-	timestamp := localTime.Format("15:04:05.0")
+	// A zero time is left out, like slog's handlers do
+	timestamp := ""
+	if !r.Time.IsZero() {
+		if a := replaceAttr(rep, nil, slog.Time(slog.TimeKey, r.Time)); a.Key != "" {
+			if a.Value.Kind() == slog.KindTime {
+				// Use the configured time zone, local time (e.g. KST via the TZ environment variable) by default
+				timestamp = inLocation(a.Value.Time(), h.location).Format(h.timeFormat)
+			} else {
+				timestamp = a.Value.String()
+			}
+		}
+	}
 
 	// Get level and color
-	levelStr := h.formatLevel(r.Level)
+	levelStr := ""
+	if a := replaceAttr(rep, nil, slog.Any(slog.LevelKey, r.Level)); a.Key != "" {
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			levelStr = h.formatLevel(level)
+		} else {
+			levelStr = paint(h.colors, levelColor(r.Level), a.Value.String())
+		}
+	}
+
+	message := ""
+	if a := replaceAttr(rep, nil, slog.String(slog.MessageKey, r.Message)); a.Key != "" {
+		message = a.Value.String()
+	}
 
 	// Get source info if requested
 	source := ""
-	if h.opts.AddSource && r.PC != 0 {
-		fs := runtime.CallersFrames([]uintptr{r.PC})
-		f, _ := fs.Next()
-		if f.File != "" {
-			// Extract just filename and function name
-			file := filepath.Base(f.File)
-			funcName := filepath.Base(f.Function) + "()"
-			// Remove package prefix from function name
-			if idx := strings.LastIndex(funcName, "."); idx != -1 {
-				funcName = funcName[idx+1:]
+	if src := recordSource(r.PC); h.opts.AddSource && src != nil {
+		if a := replaceAttr(rep, nil, slog.Any(slog.SourceKey, src)); a.Key != "" {
+			if src, ok := a.Value.Any().(*slog.Source); ok {
+				// Just the file name and function name
+				source = fmt.Sprintf("%s:%s:%d",
+					paint(h.colors, fileColor, filepath.Base(src.File)),
+					paint(h.colors, functionColor, shortFunction(src.Function)+"()"),
+					src.Line)
+			} else {
+				source = a.Value.String()
 			}
-			source = fmt.Sprintf("%s:%s:%d",
-				fileColor(file),
-				functionColor(funcName),
-				f.Line)
 		}
 	}
 
 	// Get emoji if contextual
+	top := topAttrs(ctx)
 	emoji := ""
 	if h.showEmoji {
-		contextEmoji := getContextualEmoji(r.Message)
-		if contextEmoji != "" {
-			emoji = contextEmoji
-		} else {
-			emoji = getEmojiForLevel(r.Level)
-		}
+		emoji = pickEmoji(h.theme, h.rules, withHandlerAttrs(top, h.attrs), h.groups, r)
 		if emoji != "" {
 			spacing := getEmojiSpacing(emoji)
 			emoji = emoji + spacing
@@ -110,10 +185,22 @@ func (h *PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// Format the main message
 	var msg strings.Builder
-	msg.WriteString(timeColor(timestamp))
-	msg.WriteString(" ")
-	msg.WriteString(levelStr)
-	msg.WriteString(" ")
+	if timestamp != "" {
+		msg.WriteString(paint(h.colors, timeColor, timestamp))
+		msg.WriteString(" ")
+	}
+	if levelStr != "" {
+		msg.WriteString(levelStr)
+		msg.WriteString(" ")
+	}
+
+	// Short trace ID so lines of one request are easy to spot
+	if h.showTrace {
+		if prefix := tracePrefix(ctx, h.colors); prefix != "" {
+			msg.WriteString(prefix)
+			msg.WriteString(" ")
+		}
+	}
 
 	if source != "" {
 		msg.WriteString(source)
@@ -121,131 +208,172 @@ func (h *PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	msg.WriteString(" ")
 	msg.WriteString(emoji)
-	msg.WriteString(r.Message)
+	msg.WriteString(message)
 
 	// Add attributes
-	attrs := h.formatAttrs(r)
-	if attrs != "" {
+	attrs := h.formatAttrs(top, r)
+	if len(attrs.pairs) > 0 {
 		msg.WriteString(" ")
-		msg.WriteString(attrColor(attrs))
+		msg.WriteString(paint(h.colors, attrColor, strings.Join(attrs.pairs, " ")))
 	}
 
 	msg.WriteString("\n")
 
+	// Stack traces of errors go under the line, indented
+	for _, stack := range attrs.stacks {
+		for _, line := range strings.Split(stack, "\n") {
+			msg.WriteString("    ")
+			msg.WriteString(line)
+			msg.WriteString("\n")
+		}
+	}
+
 	_, err := h.out.Write([]byte(msg.String()))
 	return err
 }
 
 // formatLevel returns a colored level string
 func (h *PrettyHandler) formatLevel(level slog.Level) string {
-	switch {
-	case level >= slog.LevelError:
-		return errorColor("ERROR")
-	case level >= slog.LevelWarn:
-		return warnColor(" WARN")
-	case level >= slog.LevelInfo:
-		return infoColor(" INFO")
-	case level >= slog.LevelDebug:
-		return debugColor("DEBUG")
-	default:
-		return "TRACE"
-	}
-}
-
-// formatAttrs formats attributes as key=value pairs
-func (h *PrettyHandler) formatAttrs(r slog.Record) string {
-	var attrs []string
-
-	// Add handler's attributes
-	for _, attr := range h.attrs {
-		if attr.Key != "" && !h.shouldSkipAttr(attr.Key) {
-			attrs = append(attrs, fmt.Sprintf("%s=%v", attr.Key, attr.Value))
+	return levelLabel(level, h.colors)
+}
+
+// formatAttrs formats the top-level, the handler's and the record's attributes,
+// keys are qualified with their groups. Handler attributes were formatted in WithAttrs already.
+func (h *PrettyHandler) formatAttrs(top []slog.Attr, r slog.Record) prettyAttrs {
+	attrs := h.formatted.clip()
+	if len(top) > 0 {
+		attrs = prettyAttrs{}
+		for _, a := range h.filter.filterAttrs(nil, top) {
+			attrs.add("", replaceAttr(h.opts.ReplaceAttr, nil, a))
 		}
+		attrs.pairs = append(attrs.pairs, h.formatted.pairs...)
+		attrs.stacks = append(attrs.stacks, h.formatted.stacks...)
 	}
 
 	// Add record's attributes
 	r.Attrs(func(a slog.Attr) bool {
-		if a.Key != "" && !h.shouldSkipAttr(a.Key) {
-			attrs = append(attrs, fmt.Sprintf("%s=%v", a.Key, a.Value))
+		if a = h.filter.filter(h.groups, a); !a.Equal(slog.Attr{}) {
+			attrs.add(h.prefix, replaceAttr(h.opts.ReplaceAttr, h.groups, a))
 		}
 		return true
 	})
 
-	if len(attrs) == 0 {
-		return ""
-	}
+	return attrs
+}
 
-	return strings.Join(attrs, " ")
+// prettyAttrs are attributes formatted for a pretty line
+type prettyAttrs struct {
+	pairs  []string // key=value
+	stacks []string // stack traces of errors, prefixed with their keys
+}
+
+// clip returns p with capacities limited so appending copies
+func (p prettyAttrs) clip() prettyAttrs {
+	return prettyAttrs{
+		pairs:  p.pairs[:len(p.pairs):len(p.pairs)],
+		stacks: p.stacks[:len(p.stacks):len(p.stacks)],
+	}
 }
 
-// shouldSkipAttr determines if an attribute should be skipped
-func (h *PrettyHandler) shouldSkipAttr(key string) bool {
-	// Skip common verbose attributes
-	skipKeys := []string{
-		"service",
-		"version",
-		"metric_name",
-		"metric_value",
-		"environment",
-		"pid",
+// add appends a as key=value pairs. Group members get the group name added
+// to prefix, groups without a key are inlined.
+func (p *prettyAttrs) add(prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			p.add(prefix, member)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	p.pairs = append(p.pairs, prefix+a.Key+"="+prettyValue(a.Value))
+	if err, ok := a.Value.Any().(error); a.Value.Kind() == slog.KindAny && ok {
+		if stack := errorStack(err); stack != "" {
+			p.stacks = append(p.stacks, prefix+a.Key+": "+stack)
+		}
 	}
+}
 
-	for _, skip := range skipKeys {
-		if key == skip {
-			return true
+// prettyValue formats a value, quoting it like slog's TextHandler if it
+// contains spaces, quotes, equal signs or unprintable characters.
+// Errors show the errors they wrap.
+func prettyValue(v slog.Value) string {
+	s := v.String()
+	if err, ok := v.Any().(error); v.Kind() == slog.KindAny && ok {
+		s = errorChain(err)
+	}
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
 		}
 	}
-	return false
+	return s
+}
+
+// inLocation returns t in loc, or in local time if loc is nil
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t.Local()
+	}
+	return t.In(loc)
 }
 
 // WithAttrs implements slog.Handler
 func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &PrettyHandler{
-		out:       h.out,
-		opts:      h.opts,
-		attrs:     append(h.attrs, attrs...),
-		groups:    h.groups,
-		showEmoji: h.showEmoji,
+	replaced := replaceAttrs(h.opts.ReplaceAttr, h.groups, h.filter.filterAttrs(h.groups, attrs))
+	if len(replaced) == 0 {
+		return h
+	}
+	h2 := h.clone()
+	h2.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], qualifyAttrs(h.groups, replaced)...)
+	for _, a := range replaced {
+		h2.formatted.add(h.prefix, a)
 	}
+	return h2
 }
 
 // WithGroup implements slog.Handler
 func (h *PrettyHandler) WithGroup(name string) slog.Handler {
-	return &PrettyHandler{
-		out:       h.out,
-		opts:      h.opts,
-		attrs:     h.attrs,
-		groups:    append(h.groups, name),
-		showEmoji: h.showEmoji,
+	if name == "" {
+		return h
 	}
+	h2 := h.clone()
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.prefix = h.prefix + name + "."
+	return h2
 }
 
-// SetupPrettyLogger sets up a logger with pretty formatting
-func SetupPrettyLogger(w io.Writer, level slog.Level, addSource bool) *slog.Logger {
-	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: addSource,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			// Remove time attribute as we handle it ourselves
-			if a.Key == slog.TimeKey {
-				return slog.Attr{}
-			}
-			// Remove level attribute as we handle it ourselves
-			if a.Key == slog.LevelKey {
-				return slog.Attr{}
-			}
-			// Remove message attribute as we handle it ourselves
-			if a.Key == slog.MessageKey {
-				return slog.Attr{}
-			}
-			// Remove source attribute as we handle it ourselves
-			if a.Key == slog.SourceKey {
-				return slog.Attr{}
-			}
-			return a
-		},
+// clone copies the handler for WithAttrs and WithGroup
+func (h *PrettyHandler) clone() *PrettyHandler {
+	return &PrettyHandler{
+		mu:         h.mu,
+		out:        h.out,
+		opts:       h.opts,
+		attrs:      h.attrs,
+		formatted:  h.formatted.clip(),
+		groups:     h.groups,
+		prefix:     h.prefix,
+		showEmoji:  h.showEmoji,
+		showTrace:  h.showTrace,
+		rules:      h.rules,
+		theme:      h.theme,
+		timeFormat: h.timeFormat,
+		location:   h.location,
+		colors:     h.colors,
+		filter:     h.filter,
 	}
+}
 
-	handler := NewPrettyHandler(w, opts)
-	return slog.New(handler)
+// SetupPrettyLogger sets up a logger with pretty formatting. The level follows
+// SetLevel once it is called. See New for more options.
+func SetupPrettyLogger(w io.Writer, level slog.Level, addSource bool) *slog.Logger {
+	return New(WithWriter(w), WithLevel(newSetupLevel(level)), WithSource(addSource))
 }
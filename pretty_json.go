@@ -6,29 +6,89 @@ import (
 	"io"
 	"log/slog"
 	"path/filepath"
-	"runtime"
 	"strings"
-
-	"github.com/fatih/color"
+	"sync"
+	"time"
 )
 
 // PrettyJSONHandler formats logs as indented JSON with colors
 type PrettyJSONHandler struct {
-	out  io.Writer
-	opts *slog.HandlerOptions
+	mu     *sync.Mutex // serializes writes, shared with the handlers from WithAttrs and WithGroup
+	out    io.Writer
+	opts   *slog.HandlerOptions
+	attrs  []slog.Attr // qualified with the groups open when they were added
+	groups []string
+	rules  *EmojiRuleSet
+	theme  *Theme
+
+	showEmoji  bool
+	timeFormat string
+	location   *time.Location
+	colors     ColorMode
+	filter     AttrFilter
 }
 
+// DefaultPrettyJSONTimeFormat is the timestamp layout of PrettyJSONHandler
+const DefaultPrettyJSONTimeFormat = "2006-01-02 15:04:05.000"
+
 // NewPrettyJSONHandler creates a new pretty JSON handler
 func NewPrettyJSONHandler(out io.Writer, opts *slog.HandlerOptions) *PrettyJSONHandler {
 	if opts == nil {
 		opts = &slog.HandlerOptions{}
 	}
 	return &PrettyJSONHandler{
-		out:  out,
-		opts: opts,
+		mu:         new(sync.Mutex),
+		out:        out,
+		opts:       opts,
+		rules:      DefaultEmojiRules(),
+		showEmoji:  true,
+		timeFormat: DefaultPrettyJSONTimeFormat,
 	}
 }
 
+// SetEmojiRules sets the rules used to pick contextual emojis, nil disables them
+func (h *PrettyJSONHandler) SetEmojiRules(rules *EmojiRuleSet) {
+	h.rules = rules
+}
+
+// SetTheme sets the glyph theme, nil follows DefaultTheme
+func (h *PrettyJSONHandler) SetTheme(theme *Theme) {
+	h.theme = theme
+}
+
+// SetShowEmoji turns the "emoji" field on or off
+func (h *PrettyJSONHandler) SetShowEmoji(show bool) {
+	h.showEmoji = show
+}
+
+// SetTimeFormat sets the timestamp layout, see time.Layout
+func (h *PrettyJSONHandler) SetTimeFormat(layout string) {
+	h.timeFormat = layout
+}
+
+// SetLocation sets the time zone of timestamps, nil uses time.Local
+func (h *PrettyJSONHandler) SetLocation(loc *time.Location) {
+	h.location = loc
+}
+
+// SetColorMode sets whether output is colored, see ColorMode
+func (h *PrettyJSONHandler) SetColorMode(mode ColorMode) {
+	h.colors = mode
+}
+
+// SetAttrFilter sets which attributes are shown, see AttrFilter
+func (h *PrettyJSONHandler) SetAttrFilter(filter AttrFilter) {
+	h.filter = filter
+}
+
+// SetSkipKeys leaves out the attributes at the given paths, see SkipKeys
+func (h *PrettyJSONHandler) SetSkipKeys(keys []string) {
+	h.filter = SkipKeys(keys...)
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *PrettyJSONHandler) handlesTopAttrs() bool { return true }
+
 // Enabled implements slog.Handler
 func (h *PrettyJSONHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	minLevel := slog.LevelInfo
@@ -40,86 +100,78 @@ func (h *PrettyJSONHandler) Enabled(ctx context.Context, level slog.Level) bool
 
 // Handle implements slog.Handler
 func (h *PrettyJSONHandler) Handle(ctx context.Context, r slog.Record) error {
-	// Use local time (KST)
-	localTime := r.Time.Local()
-
 	// Create JSON structure
 	logEntry := make(map[string]interface{})
 
-	// Basic fields
-	logEntry["time"] = localTime.Format("2006-01-02 15:04:05.000")
-	logEntry["level"] = r.Level.String()
+	// Built-in fields go through ReplaceAttr like in slog's handlers,
+	// which may rename, change or remove them
+	rep := h.opts.ReplaceAttr
+	// Attributes from ContextHandler, outside the groups
+	top := topAttrs(ctx)
+	if !r.Time.IsZero() {
+		if a := replaceAttr(rep, nil, slog.Time(slog.TimeKey, r.Time)); a.Key != "" {
+			if a.Value.Kind() == slog.KindTime {
+				// Use the configured time zone, local time (KST) by default
+				logEntry[a.Key] = inLocation(a.Value.Time(), h.location).Format(h.timeFormat)
+			} else {
+				logEntry[a.Key] = jsonValue(a.Value)
+			}
+		}
+	}
+	if a := replaceAttr(rep, nil, slog.Any(slog.LevelKey, r.Level)); a.Key != "" {
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			logEntry[a.Key] = LevelName(level)
+		} else {
+			logEntry[a.Key] = jsonValue(a.Value)
+		}
+	}
 
 	// Add emoji based on level or context
-	emoji := getContextualEmoji(r.Message)
-	if emoji == "" {
-		emoji = getEmojiForLevel(r.Level)
-	}
-	if emoji != "" {
-		logEntry["emoji"] = emoji
+	if h.showEmoji {
+		if emoji := pickEmoji(h.theme, h.rules, withHandlerAttrs(top, h.attrs), h.groups, r); emoji != "" {
+			logEntry["emoji"] = emoji
+		}
 	}
 
-	logEntry["msg"] = r.Message
+	if a := replaceAttr(rep, nil, slog.String(slog.MessageKey, r.Message)); a.Key != "" {
+		logEntry[a.Key] = jsonValue(a.Value)
+	}
 
 	// Add source if requested
-	if h.opts.AddSource && r.PC != 0 {
-		fs := runtime.CallersFrames([]uintptr{r.PC})
-		f, _ := fs.Next()
-		if f.File != "" {
-			source := make(map[string]interface{})
-			source["file"] = filepath.Base(f.File)
-			source["line"] = f.Line
-
-			funcName := filepath.Base(f.Function)
-			if idx := strings.LastIndex(funcName, "."); idx != -1 {
-				funcName = funcName[idx+1:]
+	if src := recordSource(r.PC); h.opts.AddSource && src != nil {
+		if a := replaceAttr(rep, nil, slog.Any(slog.SourceKey, src)); a.Key != "" {
+			if src, ok := a.Value.Any().(*slog.Source); ok {
+				logEntry[a.Key] = map[string]interface{}{
+					"file":     filepath.Base(src.File),
+					"line":     src.Line,
+					"function": shortFunction(src.Function),
+				}
+			} else {
+				logEntry[a.Key] = jsonValue(a.Value)
 			}
-			source["function"] = funcName
-			logEntry["source"] = source
 		}
 	}
 
-	// Add attributes
+	// Add attributes, nested in the groups open when they were added
 	attrs := make(map[string]interface{})
+	for _, a := range h.filter.filterAttrs(nil, top) {
+		addJSONAttr(attrs, replaceAttr(rep, nil, a))
+	}
+	for _, a := range h.attrs {
+		addJSONAttr(attrs, a)
+	}
+	recordAttrs := make([]slog.Attr, 0, r.NumAttrs())
 	r.Attrs(func(a slog.Attr) bool {
-		// Skip verbose attributes
-		if !shouldSkipJSONAttr(a.Key) {
-			// Handle special types
-			switch v := a.Value.Any().(type) {
-			case json.RawMessage:
-				// Try to unmarshal as JSON
-				var parsed interface{}
-				if err := json.Unmarshal(v, &parsed); err == nil {
-					attrs[a.Key] = parsed
-				} else {
-					attrs[a.Key] = string(v)
-				}
-			case []byte:
-				// Try to parse as JSON first
-				var parsed interface{}
-				if err := json.Unmarshal(v, &parsed); err == nil {
-					attrs[a.Key] = parsed
-				} else {
-					attrs[a.Key] = string(v)
-				}
-			case string:
-				// Check if it looks like JSON
-				if strings.HasPrefix(v, "{") || strings.HasPrefix(v, "[") {
-					var parsed interface{}
-					if err := json.Unmarshal([]byte(v), &parsed); err == nil {
-						attrs[a.Key] = parsed
-					} else {
-						attrs[a.Key] = v
-					}
-				} else {
-					attrs[a.Key] = v
-				}
-			default:
-				attrs[a.Key] = a.Value.Any()
+		if a = h.filter.filter(h.groups, a); !a.Equal(slog.Attr{}) {
+			if a = replaceAttr(rep, h.groups, a); !a.Equal(slog.Attr{}) {
+				recordAttrs = append(recordAttrs, a)
 			}
 		}
 		return true
 	})
+	for _, a := range qualifyAttrs(h.groups, recordAttrs) {
+		addJSONAttr(attrs, a)
+	}
 
 	if len(attrs) > 0 {
 		logEntry["attrs"] = attrs
@@ -132,64 +184,101 @@ func (h *PrettyJSONHandler) Handle(ctx context.Context, r slog.Record) error {
 	}
 
 	// Add color based on level
-	var coloredOutput string
-	switch r.Level {
-	case slog.LevelError:
-		coloredOutput = color.RedString(string(output))
-	case slog.LevelWarn:
-		coloredOutput = color.YellowString(string(output))
-	case slog.LevelInfo:
-		coloredOutput = color.GreenString(string(output))
-	case slog.LevelDebug:
-		coloredOutput = color.CyanString(string(output))
-	default:
-		coloredOutput = string(output)
-	}
+	coloredOutput := paint(h.colors, levelColor(r.Level), string(output))
 
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	_, err = h.out.Write([]byte(coloredOutput + "\n"))
 	return err
 }
 
-// WithAttrs implements slog.Handler
-func (h *PrettyJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	// For simplicity, we'll just return self
-	// In production, you'd want to store and apply these
-	return h
-}
+// addJSONAttr adds a to m, groups become nested objects and groups
+// without a key are inlined
+func addJSONAttr(m map[string]interface{}, a slog.Attr) {
+	if a.Value.Kind() != slog.KindGroup {
+		if a.Key != "" {
+			m[a.Key] = jsonValue(a.Value)
+		}
+		return
+	}
 
-// WithGroup implements slog.Handler
-func (h *PrettyJSONHandler) WithGroup(name string) slog.Handler {
-	// For simplicity, we'll just return self
-	return h
+	members := a.Value.Group()
+	if len(members) == 0 {
+		return
+	}
+	group := m
+	if a.Key != "" {
+		// Merge with the same group from WithAttrs
+		existing, ok := m[a.Key].(map[string]interface{})
+		if !ok {
+			existing = make(map[string]interface{}, len(members))
+			m[a.Key] = existing
+		}
+		group = existing
+	}
+	for _, member := range members {
+		addJSONAttr(group, member)
+	}
 }
 
-// shouldSkipJSONAttr determines if an attribute should be skipped in JSON output
-func shouldSkipJSONAttr(key string) bool {
-	// Skip less important attributes in JSON mode
-	skipKeys := []string{
-		"service",
-		"version",
-		"environment",
-		"pid",
-		"metric_name",
-		"metric_value",
+// jsonValue converts an attribute value for encoding/json, embedded JSON is decoded
+func jsonValue(value slog.Value) interface{} {
+	// Handle special types
+	switch v := value.Any().(type) {
+	case json.RawMessage:
+		// Try to unmarshal as JSON
+		var parsed interface{}
+		if err := json.Unmarshal(v, &parsed); err == nil {
+			return parsed
+		}
+		return string(v)
+	case []byte:
+		// Try to parse as JSON first
+		var parsed interface{}
+		if err := json.Unmarshal(v, &parsed); err == nil {
+			return parsed
+		}
+		return string(v)
+	case string:
+		// Check if it looks like JSON
+		if strings.HasPrefix(v, "{") || strings.HasPrefix(v, "[") {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(v), &parsed); err == nil {
+				return parsed
+			}
+		}
+		return v
+	case error:
+		// Most errors have no exported fields and would marshal as {}
+		return errorChain(v)
+	default:
+		return value.Any()
 	}
+}
 
-	for _, skip := range skipKeys {
-		if key == skip {
-			return true
-		}
+// WithAttrs implements slog.Handler
+func (h *PrettyJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	replaced := replaceAttrs(h.opts.ReplaceAttr, h.groups, h.filter.filterAttrs(h.groups, attrs))
+	if len(replaced) == 0 {
+		return h
 	}
-	return false
+	h2 := *h
+	h2.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], qualifyAttrs(h.groups, replaced)...)
+	return &h2
 }
 
-// SetupPrettyJSONLogger sets up a logger with pretty JSON formatting
-func SetupPrettyJSONLogger(w io.Writer, level slog.Level, addSource bool) *slog.Logger {
-	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: addSource,
+// WithGroup implements slog.Handler
+func (h *PrettyJSONHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
 	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
 
-	handler := NewPrettyJSONHandler(w, opts)
-	return slog.New(handler)
-}
\ No newline at end of file
+// SetupPrettyJSONLogger sets up a logger with pretty JSON formatting. The level
+// follows SetLevel once it is called. See New for more options.
+func SetupPrettyJSONLogger(w io.Writer, level slog.Level, addSource bool) *slog.Logger {
+	return New(WithWriter(w), WithLevel(newSetupLevel(level)), WithFormat(FormatPrettyJSON), WithSource(addSource))
+}
@@ -0,0 +1,121 @@
+package mojilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+)
+
+func TestPrettyJSONReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	var seen []string
+	rep := redact(&seen)
+	handler := NewPrettyJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.MessageKey {
+				a.Key = "message"
+			}
+			return rep(groups, a)
+		},
+	})
+	handler.SetColorMode(ColorNever)
+
+	slog.New(handler).Info("login", "user", "bob", slog.Group("auth", "password", "hunter2", "method", "otp"))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if _, ok := entry["time"]; ok {
+		t.Errorf("expected the time to be removed, got %v", entry)
+	}
+	if entry["message"] != "login" {
+		t.Errorf("expected the message under the renamed key, got %v", entry)
+	}
+	attrs, _ := entry["attrs"].(map[string]any)
+	auth, _ := attrs["auth"].(map[string]any)
+	if attrs["username"] != "bob" || auth["password"] != "***" || auth["method"] != "otp" {
+		t.Errorf("expected attributes to be replaced, got %v", attrs)
+	}
+	if got := strings.Join(seen, ","); got != "time,level,message,user,auth.password,auth.method" {
+		t.Errorf("unexpected ReplaceAttr calls %s", got)
+	}
+}
+
+func TestPrettyJSONWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyJSONHandler(&buf, nil)
+	handler.SetColorMode(ColorNever)
+
+	logger := slog.New(handler).With("request_id", "r1").WithGroup("http").With("method", "GET")
+	logger.Info("served", "status", 200, slog.Group("timing", "ms", 12))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	attrs, _ := entry["attrs"].(map[string]any)
+	http, _ := attrs["http"].(map[string]any)
+	timing, _ := http["timing"].(map[string]any)
+	if attrs["request_id"] != "r1" || http["method"] != "GET" || http["status"] != 200.0 || timing["ms"] != 12.0 {
+		t.Errorf("unexpected attrs %v", attrs)
+	}
+}
+
+func TestPrettyJSONErrors(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyJSONHandler(&buf, nil)
+	handler.SetColorMode(ColorNever)
+
+	slog.New(handler).Error("failed", "err", fmt.Errorf("save: %w", opaqueError{errors.New("timeout")}))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	attrs, _ := entry["attrs"].(map[string]any)
+	if attrs["err"] != "save: request failed: timeout" {
+		t.Errorf("unexpected err %v", attrs["err"])
+	}
+}
+
+func TestPrettyJSONSlogtest(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	handler.SetColorMode(ColorNever)
+	handler.SetShowEmoji(false)
+
+	err := slogtest.TestHandler(handler, func() []map[string]any {
+		return parsePrettyJSON(t, buf.Bytes())
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// parsePrettyJSON decodes a stream of PrettyJSONHandler entries without colors,
+// moving the attrs object to the top level
+func parsePrettyJSON(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var results []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var entry map[string]any
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("invalid JSON %q: %v", data, err)
+		}
+		if attrs, ok := entry["attrs"].(map[string]any); ok {
+			delete(entry, "attrs")
+			for k, v := range attrs {
+				entry[k] = v
+			}
+		}
+		results = append(results, entry)
+	}
+	return results
+}
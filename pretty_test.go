@@ -0,0 +1,217 @@
+package mojilog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+// redact hides passwords, drops the time and records the groups it sees
+func redact(seen *[]string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		*seen = append(*seen, strings.Join(append(groups, a.Key), "."))
+		switch a.Key {
+		case slog.TimeKey:
+			return slog.Attr{}
+		case "password":
+			return slog.String(a.Key, "***")
+		case "user":
+			a.Key = "username"
+		}
+		return a
+	}
+}
+
+func TestPrettyReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	var seen []string
+	handler := NewPrettyHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redact(&seen)})
+	handler.SetColorMode(ColorNever)
+	handler.SetShowEmoji(false)
+
+	logger := slog.New(handler).With("user", "bob").WithGroup("auth")
+	logger.Info("login", "password", "hunter2")
+
+	out := buf.String()
+	if !strings.HasPrefix(out, " INFO") {
+		t.Errorf("expected the time to be removed, got %q", out)
+	}
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "password=***") || !strings.Contains(out, "username=bob") {
+		t.Errorf("expected attributes to be replaced, got %q", out)
+	}
+	if got := strings.Join(seen, ","); got != "user,time,level,msg,auth.password" {
+		t.Errorf("unexpected ReplaceAttr calls %s", got)
+	}
+}
+
+func TestSetupPrettyLoggerKeepsBuiltins(t *testing.T) {
+	defer SetLevel(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	SetupPrettyLogger(&buf, slog.LevelInfo, false).Info("still here")
+	if !strings.Contains(stripANSI(buf.String()), "INFO") || !strings.Contains(buf.String(), "still here") {
+		t.Errorf("expected level and message, got %q", buf.String())
+	}
+}
+
+func TestPrettyGroups(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyHandler(&buf, nil)
+	handler.SetColorMode(ColorNever)
+	handler.SetShowEmoji(false)
+
+	logger := slog.New(handler).WithGroup("http").With("id", 7).WithGroup("request").WithGroup("empty")
+	logger.Info("served",
+		slog.Group("", "inline", true),
+		slog.Group("headers", "accept", "text/plain; q=0.9"),
+		slog.Group("none"),
+		"method", "GET",
+	)
+
+	out := buf.String()
+	want := `served http.id=7 http.request.empty.inline=true http.request.empty.headers.accept="text/plain; q=0.9" http.request.empty.method=GET`
+	if !strings.Contains(out, want) {
+		t.Errorf("expected %s in %q", want, out)
+	}
+	if strings.Contains(out, "none") {
+		t.Errorf("expected empty groups to be dropped, got %q", out)
+	}
+}
+
+// user is a LogValuer that hides everything but the name
+type user struct {
+	name     string
+	password string
+}
+
+func (u user) LogValue() slog.Value { return slog.StringValue(u.name) }
+
+func TestPrettyValues(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyHandler(&buf, nil)
+	handler.SetColorMode(ColorNever)
+	handler.SetShowEmoji(false)
+
+	logger := slog.New(handler).With("user", user{"bob", "secret"})
+	logger.Error("failed",
+		"err", errors.Join(errors.New("disk full"), fmt.Errorf("retry: %w", stackError{"boom"})),
+		slog.Group("job", "owner", user{"alice", "hunter2"}),
+	)
+
+	lines := strings.Split(buf.String(), "\n")
+	want := `failed user=bob err="[disk full; retry: boom]" job.owner=alice`
+	if !strings.HasSuffix(lines[0], want) {
+		t.Errorf("expected line to end with %s, got %q", want, lines[0])
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("expected LogValuers to be resolved, got %q", buf.String())
+	}
+	stack := "    err: boom\n    main.load\n    \t/src/main.go:12\n"
+	if got := strings.Join(lines[1:], "\n"); got != stack {
+		t.Errorf("expected stack %q under the line, got %q", stack, got)
+	}
+}
+
+func TestPrettySlogtest(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	handler.SetColorMode(ColorNever)
+	handler.SetShowEmoji(false)
+	handler.SetTimeFormat(time.RFC3339Nano)
+
+	err := slogtest.TestHandler(handler, func() []map[string]any {
+		var results []map[string]any
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			results = append(results, parsePrettyLine(t, line))
+		}
+		return results
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// parsePrettyLine parses a line of PrettyHandler output without colors, emojis
+// or source. Dotted keys become nested maps.
+func parsePrettyLine(t *testing.T, line string) map[string]any {
+	t.Helper()
+	m := make(map[string]any)
+	tokens := splitPrettyLine(line)
+
+	if len(tokens) > 0 {
+		if ts, err := time.Parse(time.RFC3339Nano, tokens[0]); err == nil {
+			m[slog.TimeKey] = ts
+			tokens = tokens[1:]
+		}
+	}
+	if len(tokens) > 0 {
+		if _, ok := LevelByName(tokens[0]); ok {
+			m[slog.LevelKey] = tokens[0]
+			tokens = tokens[1:]
+		}
+	}
+
+	var msg []string
+	for len(tokens) > 0 && !strings.Contains(tokens[0], "=") {
+		msg = append(msg, tokens[0])
+		tokens = tokens[1:]
+	}
+	m[slog.MessageKey] = strings.Join(msg, " ")
+
+	for _, token := range tokens {
+		key, value, _ := strings.Cut(token, "=")
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				t.Fatalf("bad quoted value in %q: %v", line, err)
+			}
+			value = unquoted
+		}
+		group := m
+		path := strings.Split(key, ".")
+		for _, name := range path[:len(path)-1] {
+			sub, ok := group[name].(map[string]any)
+			if !ok {
+				sub = make(map[string]any)
+				group[name] = sub
+			}
+			group = sub
+		}
+		group[path[len(path)-1]] = value
+	}
+	return m
+}
+
+// splitPrettyLine splits a line at spaces outside of quoted values
+func splitPrettyLine(line string) []string {
+	var tokens []string
+	var token strings.Builder
+	quoted, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+			continue
+		}
+		token.WriteRune(r)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
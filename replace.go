@@ -0,0 +1,74 @@
+package mojilog
+
+import (
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// replaceAttr resolves a and applies fn to it the way slog's handlers do:
+// groups are not passed to fn, their members are, with the group appended
+// to groups. Empty attributes and groups are returned as slog.Attr{}.
+func replaceAttr(fn func(groups []string, a slog.Attr) slog.Attr, groups []string, a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		members := a.Value.Group()
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		replaced := make([]slog.Attr, 0, len(members))
+		for _, m := range members {
+			if m = replaceAttr(fn, groups, m); !m.Equal(slog.Attr{}) {
+				replaced = append(replaced, m)
+			}
+		}
+		if len(replaced) == 0 {
+			return slog.Attr{}
+		}
+		a.Value = slog.GroupValue(replaced...)
+		return a
+	}
+
+	if fn != nil {
+		a = fn(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Key == "" {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// replaceAttrs applies replaceAttr to attrs and drops the empty ones
+func replaceAttrs(fn func(groups []string, a slog.Attr) slog.Attr, groups []string, attrs []slog.Attr) []slog.Attr {
+	replaced := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a = replaceAttr(fn, groups, a); !a.Equal(slog.Attr{}) {
+			replaced = append(replaced, a)
+		}
+	}
+	return replaced
+}
+
+// recordSource returns the source of pc, or nil if it is unknown
+func recordSource(pc uintptr) *slog.Source {
+	if pc == 0 {
+		return nil
+	}
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	if f.File == "" {
+		return nil
+	}
+	return &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+}
+
+// shortFunction returns the function name without its package, e.g. "Get" for "myapp/cache.(*Cache).Get"
+func shortFunction(function string) string {
+	name := filepath.Base(function)
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		name = name[idx+1:]
+	}
+	return name
+}
@@ -0,0 +1,245 @@
+package mojilog
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Category is the semantic meaning of an emoji, themes map categories to glyphs
+type Category string
+
+// Level categories
+const (
+	CategoryTrace    Category = "trace"
+	CategoryDebug    Category = "debug"
+	CategoryInfo     Category = "info"
+	CategoryNotice   Category = "notice"
+	CategoryWarn     Category = "warn"
+	CategoryError    Category = "error"
+	CategoryCritical Category = "critical"
+	CategoryFatal    Category = "fatal"
+)
+
+// Contextual categories used by the default emoji rules
+const (
+	CategoryHealthExcellent Category = "health-excellent"
+	CategoryHealthGood      Category = "health-good"
+	CategoryHealthDegraded  Category = "health-degraded"
+	CategoryHealthCritical  Category = "health-critical"
+	CategoryShutdown        Category = "shutdown"
+	CategoryStart           Category = "start"
+	CategoryMetrics         Category = "metrics"
+	CategorySuccess         Category = "success"
+	CategoryCleanup         Category = "cleanup"
+	CategoryConfig          Category = "config"
+	CategoryConnect         Category = "connect"
+	CategoryFailure         Category = "failure"
+	CategoryGame            Category = "game"
+	CategoryLoading         Category = "loading"
+	CategoryCreate          Category = "create"
+)
+
+// Theme maps categories to the glyphs printed in front of log messages
+type Theme struct {
+	// Name is used to look the theme up, e.g. "unicode" or "ascii"
+	Name string
+	// Glyphs maps each category to its glyph, a missing category prints nothing
+	Glyphs map[Category]string
+	// AllowLiteral lets rules without a category use their own Emoji.
+	// Themes for terminals that can't render emojis should leave it off.
+	AllowLiteral bool
+}
+
+// Glyph returns the glyph for a category
+func (t *Theme) Glyph(c Category) string {
+	if t == nil {
+		return ""
+	}
+	return t.Glyphs[c]
+}
+
+// ruleGlyph returns the glyph for a matched emoji rule
+func (t *Theme) ruleGlyph(rule *EmojiRule) string {
+	if rule.Category != "" {
+		if glyph, ok := t.Glyphs[rule.Category]; ok {
+			return glyph
+		}
+	}
+	if t.AllowLiteral {
+		return rule.Emoji
+	}
+	return ""
+}
+
+// Built-in themes
+var (
+	// ThemeUnicode is the default emoji theme
+	ThemeUnicode = &Theme{
+		Name:         "unicode",
+		AllowLiteral: true,
+		Glyphs: map[Category]string{
+			CategoryTrace:           "📝",
+			CategoryDebug:           "🔍",
+			CategoryInfo:            "ℹ️",
+			CategoryNotice:          "📣",
+			CategoryWarn:            "⚠️",
+			CategoryError:           "❌",
+			CategoryCritical:        "🚨",
+			CategoryFatal:           "💀",
+			CategoryHealthExcellent: "💚",
+			CategoryHealthGood:      "🟡",
+			CategoryHealthDegraded:  "🟠",
+			CategoryHealthCritical:  "🔴",
+			CategoryShutdown:        "🛑",
+			CategoryStart:           "🚀",
+			CategoryMetrics:         "📊",
+			CategorySuccess:         "🎉",
+			CategoryCleanup:         "🧹",
+			CategoryConfig:          "⚙️",
+			CategoryConnect:         "🔌",
+			CategoryFailure:         "❌",
+			CategoryGame:            "🎰",
+			CategoryLoading:         "⏳",
+			CategoryCreate:          "🆕",
+		},
+	}
+
+	// ThemeASCII uses plain bracketed markers such as [!] and [i]
+	ThemeASCII = &Theme{
+		Name: "ascii",
+		Glyphs: map[Category]string{
+			CategoryTrace:           "[t]",
+			CategoryDebug:           "[d]",
+			CategoryInfo:            "[i]",
+			CategoryNotice:          "[#]",
+			CategoryWarn:            "[!]",
+			CategoryError:           "[x]",
+			CategoryCritical:        "[!!]",
+			CategoryFatal:           "[XX]",
+			CategoryHealthExcellent: "[+]",
+			CategoryHealthGood:      "[~]",
+			CategoryHealthDegraded:  "[-]",
+			CategoryHealthCritical:  "[X]",
+			CategoryShutdown:        "[v]",
+			CategoryStart:           "[^]",
+			CategoryMetrics:         "[%]",
+			CategorySuccess:         "[*]",
+			CategoryCleanup:         "[c]",
+			CategoryConfig:          "[=]",
+			CategoryConnect:         "[@]",
+			CategoryFailure:         "[x]",
+			CategoryGame:            "[$]",
+			CategoryLoading:         "[.]",
+			CategoryCreate:          "[n]",
+		},
+	}
+
+	// ThemeNerdFont uses Font Awesome glyphs from a patched Nerd Font
+	ThemeNerdFont = &Theme{
+		Name:         "nerdfont",
+		AllowLiteral: true,
+		Glyphs: map[Category]string{
+			CategoryTrace:           "\uf040", // pencil
+			CategoryDebug:           "\uf188", // bug
+			CategoryInfo:            "\uf05a", // info-circle
+			CategoryNotice:          "\uf0a1", // bullhorn
+			CategoryWarn:            "\uf071", // exclamation-triangle
+			CategoryError:           "\uf057", // times-circle
+			CategoryCritical:        "\uf0e7", // bolt
+			CategoryFatal:           "\uf05e", // ban
+			CategoryHealthExcellent: "\uf004", // heart
+			CategoryHealthGood:      "\uf111", // circle
+			CategoryHealthDegraded:  "\uf06a", // exclamation-circle
+			CategoryHealthCritical:  "\uf1e2", // bomb
+			CategoryShutdown:        "\uf011", // power-off
+			CategoryStart:           "\uf135", // rocket
+			CategoryMetrics:         "\uf080", // bar-chart
+			CategorySuccess:         "\uf00c", // check
+			CategoryCleanup:         "\uf1f8", // trash
+			CategoryConfig:          "\uf013", // cog
+			CategoryConnect:         "\uf1e6", // plug
+			CategoryFailure:         "\uf00d", // times
+			CategoryGame:            "\uf11b", // gamepad
+			CategoryLoading:         "\uf110", // spinner
+			CategoryCreate:          "\uf067", // plus
+		},
+	}
+
+	// ThemeNone prints no glyphs at all
+	ThemeNone = &Theme{
+		Name:   "none",
+		Glyphs: map[Category]string{},
+	}
+)
+
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]*Theme{
+		ThemeUnicode.Name:  ThemeUnicode,
+		ThemeASCII.Name:    ThemeASCII,
+		ThemeNerdFont.Name: ThemeNerdFont,
+		ThemeNone.Name:     ThemeNone,
+	}
+
+	defaultTheme atomic.Pointer[Theme]
+)
+
+func init() {
+	defaultTheme.Store(ThemeUnicode)
+}
+
+// RegisterTheme makes a theme available by name, replacing any theme with the same name
+func RegisterTheme(t *Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[t.Name] = t
+}
+
+// LookupTheme returns the theme registered under name
+func LookupTheme(name string) (*Theme, error) {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+
+	names := make([]string, 0, len(themes))
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown theme %q (available: %v)", name, names)
+}
+
+// SetDefaultTheme selects the theme used by handlers that don't set their own.
+// Call it before InitGlobal or the Setup functions to pick the theme of those loggers.
+func SetDefaultTheme(name string) error {
+	t, err := LookupTheme(name)
+	if err != nil {
+		return err
+	}
+	defaultTheme.Store(t)
+	return nil
+}
+
+// DefaultTheme returns the theme used by handlers that don't set their own
+func DefaultTheme() *Theme {
+	return defaultTheme.Load()
+}
+
+// pickEmoji returns the glyph for a record: a matching contextual rule wins over the level glyph
+func pickEmoji(theme *Theme, rules *EmojiRuleSet, handlerAttrs []slog.Attr, groups []string, r slog.Record) string {
+	if theme == nil {
+		theme = DefaultTheme()
+	}
+	if rule, ok := rules.matchRecord(handlerAttrs, groups, r); ok {
+		if glyph := theme.ruleGlyph(&rule); glyph != "" {
+			return glyph
+		}
+	}
+	return levelGlyph(theme, r.Level)
+}
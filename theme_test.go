@@ -0,0 +1,68 @@
+package mojilog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestThemes(t *testing.T) {
+	rules := DefaultEmojiRules()
+	if err := rules.Add(EmojiRule{Name: "deploy", Emoji: "🚢", Keywords: []string{"deploy"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		theme    string
+		level    slog.Level
+		msg      string
+		expected string
+	}{
+		{"ascii", slog.LevelWarn, "disk almost full", "[!] disk almost full"},
+		{"ascii", slog.LevelInfo, "Starting server", "[^] Starting server"},
+		{"ascii", slog.LevelInfo, "deploy done", "[i] deploy done"},
+		{"unicode", slog.LevelInfo, "deploy done", "🚢 deploy done"},
+		{"nerdfont", slog.LevelError, "boom", "  boom"},
+		{"none", slog.LevelError, "Connection failed", " Connection failed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.theme+"/"+tc.msg, func(t *testing.T) {
+			theme, err := LookupTheme(tc.theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			handler := NewPrettyHandler(&buf, nil)
+			handler.SetEmojiRules(rules)
+			handler.SetTheme(theme)
+			slog.New(handler).Log(context.Background(), tc.level, tc.msg)
+
+			if !strings.HasSuffix(buf.String(), tc.expected+"\n") {
+				t.Errorf("expected line to end with %q, got %q", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestSetDefaultTheme(t *testing.T) {
+	defer SetDefaultTheme("unicode")
+
+	if err := SetDefaultTheme("klingon"); err == nil {
+		t.Fatal("expected unknown theme to be rejected")
+	}
+	if err := SetDefaultTheme("ascii"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := SetupLogger(&buf, slog.LevelInfo, "text", false)
+	logger.Info("hello")
+
+	if !strings.Contains(buf.String(), `msg="[i] hello"`) {
+		t.Errorf("expected ascii glyph in output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,131 @@
+package mojilog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// topAttrsKey is the context key of attributes that belong at the top level
+// of the records handled with that context, outside any groups
+type topAttrsKey struct{}
+
+// withTopAttrs returns ctx carrying attrs after those it carries already
+func withTopAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if prev := topAttrs(ctx); len(prev) > 0 {
+		attrs = append(prev[:len(prev):len(prev)], attrs...)
+	}
+	return context.WithValue(ctx, topAttrsKey{}, attrs)
+}
+
+// topAttrs returns the attributes added with withTopAttrs
+func topAttrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(topAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// withHandlerAttrs returns the top-level attributes followed by the handler's
+// attributes, e.g. for matching emoji rules
+func withHandlerAttrs(top, attrs []slog.Attr) []slog.Attr {
+	if len(top) == 0 {
+		return attrs
+	}
+	return append(top[:len(top):len(top)], attrs...)
+}
+
+// topAttrsHandler is implemented by handlers that log the attributes of
+// withTopAttrs outside their groups
+type topAttrsHandler interface {
+	handlesTopAttrs() bool
+}
+
+// handlesTopAttrs reports whether h logs the attributes of withTopAttrs
+func handlesTopAttrs(h slog.Handler) bool {
+	t, ok := h.(topAttrsHandler)
+	return ok && t.handlesTopAttrs()
+}
+
+// topLevel returns h if it logs the attributes of withTopAttrs, otherwise h
+// wrapped in a topLevelHandler
+func topLevel(h slog.Handler) slog.Handler {
+	if handlesTopAttrs(h) {
+		return h
+	}
+	return &topLevelHandler{wrapped: h, flat: h}
+}
+
+// topLevelHandler logs the attributes of withTopAttrs for handlers that don't,
+// e.g. slog's JSON handler. Without groups they are added to the record.
+// With groups the record goes to flat, the handler before the first group,
+// with the groups and the attributes added in them as record attributes.
+type topLevelHandler struct {
+	wrapped slog.Handler  // with every WithAttrs and WithGroup applied
+	flat    slog.Handler  // with the WithAttrs calls before the first group applied
+	groups  []string      // groups not applied to flat
+	members [][]slog.Attr // attributes added in each of groups
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *topLevelHandler) handlesTopAttrs() bool { return true }
+
+// Enabled implements slog.Handler
+func (h *topLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.wrapped.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *topLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	top := topAttrs(ctx)
+	if len(top) == 0 {
+		return h.wrapped.Handle(ctx, r)
+	}
+	if len(h.groups) == 0 {
+		// Records share attribute storage, so clone before adding to it
+		r = r.Clone()
+		r.AddAttrs(top...)
+		return h.wrapped.Handle(ctx, r)
+	}
+
+	inner := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		inner = append(inner, a)
+		return true
+	})
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		members := append(h.members[i][:len(h.members[i]):len(h.members[i])], inner...)
+		inner = []slog.Attr{{Key: h.groups[i], Value: slog.GroupValue(members...)}}
+	}
+	flat := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	flat.AddAttrs(top...)
+	flat.AddAttrs(inner...)
+	return h.flat.Handle(ctx, flat)
+}
+
+// WithAttrs implements slog.Handler
+func (h *topLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.wrapped = h.wrapped.WithAttrs(attrs)
+	if len(h.groups) == 0 {
+		h2.flat = h2.wrapped
+		return &h2
+	}
+	h2.members = make([][]slog.Attr, len(h.members))
+	copy(h2.members, h.members)
+	last := h2.members[len(h2.members)-1]
+	h2.members[len(h2.members)-1] = append(last[:len(last):len(last)], attrs...)
+	return &h2
+}
+
+// WithGroup implements slog.Handler
+func (h *topLevelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.wrapped = h.wrapped.WithGroup(name)
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2.members = append(h.members[:len(h.members):len(h.members)], nil)
+	return &h2
+}
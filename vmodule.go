@@ -0,0 +1,215 @@
+package mojilog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// VModuleEnv is the environment variable read by InitGlobal for module levels,
+// in the format accepted by ParseVModule
+const VModuleEnv = "MOJILOG_VMODULE"
+
+// VModuleHandler wraps another handler and applies the module level overrides
+// set with SetModuleLevel or SetVModule. A record's module is the package path
+// and function of its PC, e.g. "github.com/acme/myapp/internal/cache/(*Cache).Get".
+//
+// A pattern matches the package path or the module, either whole or from any "/"
+// onwards, so "myapp/internal/cache/*" matches the example above.
+// "*" matches any run of characters including "/", "?" matches one character.
+// Records without an override follow the wrapped handler's own level.
+type VModuleHandler struct {
+	wrapped slog.Handler
+}
+
+// NewVModuleHandler creates a handler applying module levels to the given handler
+func NewVModuleHandler(wrapped slog.Handler) *VModuleHandler {
+	return &VModuleHandler{wrapped: wrapped}
+}
+
+// handlesTopAttrs implements topAttrsHandler
+func (h *VModuleHandler) handlesTopAttrs() bool { return handlesTopAttrs(h.wrapped) }
+
+// Enabled implements slog.Handler. The caller isn't known yet, so this lets
+// a record through if any override might.
+func (h *VModuleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if p := moduleLevels.Load(); p != nil {
+		for _, ml := range *p {
+			if level >= ml.level {
+				return true
+			}
+		}
+	}
+	return h.wrapped.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *VModuleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if level, ok := moduleLevelFor(r.PC); ok {
+		if r.Level < level {
+			return nil
+		}
+	} else if !h.wrapped.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.wrapped.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *VModuleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &VModuleHandler{wrapped: h.wrapped.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h *VModuleHandler) WithGroup(name string) slog.Handler {
+	return &VModuleHandler{wrapped: h.wrapped.WithGroup(name)}
+}
+
+// ParseVModule parses a comma-separated list of pattern=level pairs,
+// e.g. "myapp/internal/cache/*=debug,*=warn"
+func ParseVModule(spec string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, name, ok := strings.Cut(entry, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("vmodule entry %q: want pattern=level", entry)
+		}
+		level, ok := LevelByName(name)
+		if !ok {
+			return nil, fmt.Errorf("vmodule entry %q: unknown level %q", entry, strings.TrimSpace(name))
+		}
+		levels[pattern] = level
+	}
+	return levels, nil
+}
+
+// SetVModule replaces all module level overrides with the ones in spec,
+// see ParseVModule. An empty spec clears them.
+func SetVModule(spec string) error {
+	parsed, err := ParseVModule(spec)
+	if err != nil {
+		return err
+	}
+
+	levels := make([]moduleLevel, 0, len(parsed))
+	for pattern, level := range parsed {
+		levels = append(levels, moduleLevel{pattern: pattern, level: level})
+	}
+
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	storeModuleLevels(levels)
+	return nil
+}
+
+// pcModule is the resolved module of a PC and its override as of generation gen
+type pcModule struct {
+	pkg    string
+	module string
+	gen    uint64
+	level  slog.Level
+	ok     bool
+}
+
+// pcModules caches a *pcModule per PC
+var pcModules sync.Map
+
+// moduleLevelFor returns the override for the module of pc, if there is one
+func moduleLevelFor(pc uintptr) (slog.Level, bool) {
+	p := moduleLevels.Load()
+	if pc == 0 || p == nil || len(*p) == 0 {
+		return 0, false
+	}
+
+	gen := moduleGen.Load()
+	var m pcModule
+	if cached, ok := pcModules.Load(pc); ok {
+		m = *cached.(*pcModule)
+		if m.gen == gen {
+			return m.level, m.ok
+		}
+	} else {
+		m.pkg, m.module = resolveModule(pc)
+	}
+
+	// Overrides are ordered most specific first
+	m.gen, m.ok = gen, false
+	for _, ml := range *p {
+		if matchModule(ml.pattern, m.pkg) || matchModule(ml.pattern, m.module) {
+			m.level, m.ok = ml.level, true
+			break
+		}
+	}
+	pcModules.Store(pc, &m)
+	return m.level, m.ok
+}
+
+// resolveModule returns the package path and "package/function" of pc
+func resolveModule(pc uintptr) (pkg, module string) {
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	if f.Function == "" {
+		return "", ""
+	}
+
+	// The package path ends at the first dot after the last slash,
+	// e.g. "github.com/acme/myapp/internal/cache.(*Cache).Get"
+	slash := strings.LastIndex(f.Function, "/")
+	dot := strings.Index(f.Function[slash+1:], ".")
+	if dot == -1 {
+		return f.Function, f.Function
+	}
+	dot += slash + 1
+	return f.Function[:dot], f.Function[:dot] + "/" + f.Function[dot+1:]
+}
+
+// matchModule reports whether pattern matches s, or the part of s after any "/"
+func matchModule(pattern, s string) bool {
+	if s == "" {
+		return false
+	}
+	if globMatch(pattern, s) {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' && globMatch(pattern, s[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches s against a pattern where "*" matches any run of
+// characters and "?" matches a single byte
+func globMatch(pattern, s string) bool {
+	px, sx := 0, 0
+	starPx, starSx := -1, 0
+	for sx < len(s) {
+		switch {
+		case px < len(pattern) && pattern[px] == '*':
+			starPx, starSx = px, sx
+			px++
+		case px < len(pattern) && (pattern[px] == '?' || pattern[px] == s[sx]):
+			px++
+			sx++
+		case starPx != -1:
+			// Let the last star swallow one more byte
+			starSx++
+			px, sx = starPx+1, starSx
+		default:
+			return false
+		}
+	}
+	for px < len(pattern) && pattern[px] == '*' {
+		px++
+	}
+	return px == len(pattern)
+}
@@ -0,0 +1,83 @@
+package mojilog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	testCases := []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"*", "github.com/acme/myapp/cache/Get", true},
+		{"myapp/internal/cache/*", "github.com/acme/myapp/internal/cache/(*Cache).Get", true},
+		{"myapp/internal/cache/*", "github.com/acme/myapp/internal/cachex/Get", false},
+		{"myapp/internal/cache", "github.com/acme/myapp/internal/cache", true},
+		{"myapp/*/Get", "github.com/acme/myapp/internal/cache/Get", true},
+		{"myapp/ca?he/*", "github.com/acme/myapp/cache/Get", true},
+		{"app/cache/*", "github.com/acme/myapp/cache/Get", false},
+	}
+
+	for _, tc := range testCases {
+		if got := matchModule(tc.pattern, tc.s); got != tc.expected {
+			t.Errorf("matchModule(%q, %q): expected %v, got %v", tc.pattern, tc.s, tc.expected, got)
+		}
+	}
+}
+
+func TestParseVModule(t *testing.T) {
+	levels, err := ParseVModule("myapp/internal/cache/*=debug, *=warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels["myapp/internal/cache/*"] != slog.LevelDebug || levels["*"] != slog.LevelWarn {
+		t.Errorf("unexpected levels %v", levels)
+	}
+
+	for _, spec := range []string{"cache", "=debug", "cache=loud"} {
+		if _, err := ParseVModule(spec); err == nil {
+			t.Errorf("ParseVModule(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestVModuleHandler(t *testing.T) {
+	defer SetVModule("")
+
+	var buf bytes.Buffer
+	logger := slog.New(NewVModuleHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true})))
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug to be filtered, got %q", buf.String())
+	}
+
+	if err := SetVModule("mojilog/TestVModule*=debug,*=error"); err != nil {
+		t.Fatal(err)
+	}
+	logger.With("k", "v").Debug("visible")
+	if !strings.Contains(buf.String(), "visible") {
+		t.Errorf("expected debug from the matching function, got %q", buf.String())
+	}
+
+	buf.Reset()
+	logFromHelper(logger, "elsewhere")
+	if buf.Len() != 0 {
+		t.Errorf("expected the catch-all pattern to filter other functions, got %q", buf.String())
+	}
+
+	// Cached lookups follow later changes
+	buf.Reset()
+	SetModuleLevel("mojilog/logFromHelper", slog.LevelInfo)
+	logFromHelper(logger, "now visible")
+	if !strings.Contains(buf.String(), "now visible") {
+		t.Errorf("expected the more specific pattern to win, got %q", buf.String())
+	}
+}
+
+func logFromHelper(logger *slog.Logger, msg string) {
+	logger.Info(msg)
+}